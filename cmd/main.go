@@ -1,11 +1,15 @@
 package main
 
 import (
+	"os"
+
 	_ "github.com/go-sql-driver/mysql" // 导入mysql驱动
 	"github.com/wangyingjie930/nexus-pkg/bootstrap"
 	"github.com/wangyingjie930/nexus-pkg/logger"
 	"github.com/wangyingjie930/nexus-promotion/internal/application"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/rule"
 	"github.com/wangyingjie930/nexus-promotion/internal/interfaces"
 	"go.opentelemetry.io/otel"
 	"gorm.io/driver/mysql"
@@ -14,6 +18,7 @@ import (
 
 const (
 	serviceName = "promotion-service"
+	timezoneEnv = "PROMOTION_TIMEZONE" // 促销规则使用的时区
 )
 
 func main() {
@@ -34,7 +39,7 @@ func main() {
 
 			// 2. **自动迁移 (基础设施)**
 			// 使用在 infrastructure 包中定义的 GORM 模型
-			err = db.AutoMigrate(&infrastructure.PromotionTemplateModel{}, &infrastructure.UserCouponModel{})
+			err = db.AutoMigrate(&infrastructure.PromotionTemplateModel{}, &infrastructure.UserCouponModel{}, &infrastructure.StackingPolicyModel{})
 			if err != nil {
 				logger.Logger.Error().Err(err).Msgf("WARN: failed to auto migrate gorm models: %v", err)
 			}
@@ -42,17 +47,38 @@ func main() {
 			// 3. **创建仓储实例 (基础设施)**
 			couponRepository := infrastructure.NewGormCouponRepository(db)
 			templateRepo := infrastructure.NewGormPromotionTemplateRepository(db)
+			stackingRepo := infrastructure.NewGormStackingPolicyRepository(db)
+
+			// 4. **创建规则引擎 (基础设施)**
+			// 工程师编写 CEL 规则，运营工具生成 JSON 规则，按模板的规则语言分派
+			// 规则和公式中的 hourOfDay、dayOfWeek 按同一个时区计算，如 PROMOTION_TIMEZONE=Asia/Shanghai，未配置时使用服务器本地时区
+			loc, err := rule.LoadTimezone(os.Getenv(timezoneEnv))
+			if err != nil {
+				logger.Logger.Fatal().Err(err).Msg("failed to load promotion timezone")
+			}
+			celEngine, err := rule.NewCelRuleEngine(rule.WithTimezone(loc))
+			if err != nil {
+				logger.Logger.Fatal().Err(err).Msg("failed to create cel rule engine")
+			}
+			ruleEngine := rule.NewRoutingRuleEngine(map[domain.RuleLanguage]domain.RuleEngine{
+				domain.RuleLanguageCEL:  celEngine,
+				domain.RuleLanguageJSON: rule.NewJSONRuleEngineAdapter(),
+			})
 
-			// 4. **创建应用服务实例 (应用层)**
-			// 将仓储接口注入到应用服务中
+			// 5. **创建应用服务实例 (应用层)**
+			// 将仓储接口和规则引擎注入到应用服务中
 			tracer := otel.Tracer(serviceName)
-			promoService := application.NewPromotionService(infrastructure.NewGormUnitOfWork(db), templateRepo, couponRepository, tracer)
+			promoService, err := application.NewPromotionService(infrastructure.NewGormUnitOfWork(db), templateRepo, couponRepository, stackingRepo, ruleEngine, tracer,
+				application.WithTimezone(loc))
+			if err != nil {
+				logger.Logger.Fatal().Err(err).Msg("failed to create promotion service")
+			}
 
-			// 5. **创建HTTP处理器 (接口层)**
+			// 6. **创建HTTP处理器 (接口层)**
 			// 将应用服务注入到HTTP处理器中
 			promoHandler := interfaces.NewPromotionHandler(promoService)
 
-			// 6. **启动服务并注册路由**
+			// 7. **启动服务并注册路由**
 			promoHandler.RegisterRoutes(appCtx.Mux)
 
 			logger.Logger.Printf("✅ Promotion service routes registered.")
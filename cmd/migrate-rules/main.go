@@ -0,0 +1,83 @@
+// migrate-rules 将所有激活模板中的 JSON 规则转换为等价的 CEL 表达式，统一走编译并缓存的 CEL 执行路径。
+//
+// 默认只打印预览，确认无误后加上 -apply 写入数据库：
+//
+//	go run ./cmd/migrate-rules -dsn "root:root@tcp(127.0.0.1:3306)/promotion?charset=utf8mb4&parseTime=True&loc=Local" -apply
+//
+// 写入时每个转换成功的模板会创建一个使用 CEL 的新版本并停用旧版本，已发放的券仍引用旧版本，
+// 结果中的 template_id 和 new_template_id 记录了新旧版本的对应关系。
+// 转换失败的模板保持原样，结果中会指明出错条件在规则树中的位置；存在失败时以非零状态码退出。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	_ "github.com/go-sql-driver/mysql" // 导入mysql驱动
+	"github.com/wangyingjie930/nexus-promotion/internal/application"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/rule"
+	"go.opentelemetry.io/otel"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dsn := flag.String("dsn", "", "MySQL DSN of the promotion database")
+	apply := flag.Bool("apply", false, "write the converted rules to the database instead of only printing them")
+	timezone := flag.String("timezone", os.Getenv("PROMOTION_TIMEZONE"), "IANA timezone used by rules, must match the promotion service (defaults to $PROMOTION_TIMEZONE or the local timezone)")
+	flag.Parse()
+	if *dsn == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := gorm.Open(mysql.Open(*dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	// 与 main.go 保持一致的依赖注入
+	loc, err := rule.LoadTimezone(*timezone)
+	if err != nil {
+		log.Fatalf("failed to load timezone: %v", err)
+	}
+	celEngine, err := rule.NewCelRuleEngine(rule.WithTimezone(loc))
+	if err != nil {
+		log.Fatalf("failed to create cel rule engine: %v", err)
+	}
+	ruleEngine := rule.NewRoutingRuleEngine(map[domain.RuleLanguage]domain.RuleEngine{
+		domain.RuleLanguageCEL:  celEngine,
+		domain.RuleLanguageJSON: rule.NewJSONRuleEngineAdapter(),
+	})
+	promoService, err := application.NewPromotionService(
+		infrastructure.NewGormUnitOfWork(db),
+		infrastructure.NewGormPromotionTemplateRepository(db),
+		infrastructure.NewGormCouponRepository(db),
+		infrastructure.NewGormStackingPolicyRepository(db),
+		ruleEngine,
+		otel.Tracer("migrate-rules"),
+		application.WithTimezone(loc),
+	)
+	if err != nil {
+		log.Fatalf("failed to create promotion service: %v", err)
+	}
+
+	report, err := promoService.MigrateRulesToCEL(context.Background(), *apply)
+	if err != nil {
+		log.Fatalf("failed to migrate rules: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// 结算页展示给用户的不可用原因文案
+const (
+	checkoutMessageNotApplicable = "当前商品不满足使用条件"
+	checkoutMessageStoreOnly     = "仅限指定店铺商品使用"
+	checkoutMessageExpired       = "已过期"
+	checkoutMessageFrozen        = "已被其他订单占用"
+	checkoutMessageUnavailable   = "暂不可用"
+)
+
+// toCheckoutCouponsResponse 将券的评估结果分为可用和不可用两组。
+// 规则满足但单独使用算不出优惠的券（如未达门槛）归入不可用，并尽量给出“差¥23可用”之类的提示。
+// 已使用的券不会出现在结算页中。
+func toCheckoutCouponsResponse(evaluations []*couponEvaluation) *CheckoutCouponsResponse {
+	resp := &CheckoutCouponsResponse{
+		Available:   make([]*CheckoutCouponResponse, 0),
+		Unavailable: make([]*CheckoutCouponResponse, 0),
+	}
+
+	for _, e := range evaluations {
+		if e.outcome == CouponOutcomeUsed {
+			continue
+		}
+
+		item := &CheckoutCouponResponse{UserCouponResponse: *toUserCouponResponse(e.coupon)}
+		if e.template != nil {
+			item.TemplateName = e.template.Name
+			item.TemplateDescription = e.template.Description
+		}
+
+		if e.candidate == nil {
+			item.ReasonCode = e.outcome
+			item.ReasonMessage = checkoutMessage(e)
+			resp.Unavailable = append(resp.Unavailable, item)
+			continue
+		}
+
+		offer, err := e.candidate.strategy.Calculate(e.fact, e.template)
+		switch {
+		case err != nil:
+			item.ReasonCode = CouponOutcomeCalculationError
+			item.ReasonMessage = checkoutMessageUnavailable
+			resp.Unavailable = append(resp.Unavailable, item)
+		case offer == nil || offer.Value() <= 0:
+			item.ReasonCode = CouponOutcomeBelowThreshold
+			item.ReasonMessage = checkoutMessageNotApplicable
+			if shortfall := shortfallOf(e); !shortfall.IsZero() {
+				item.Shortfall = toShortfallResponse(shortfall)
+				item.ReasonMessage = fmt.Sprintf("差%s可用", describeShortfall(shortfall))
+			}
+			resp.Unavailable = append(resp.Unavailable, item)
+		default:
+			item.Offer = toDiscountApplicationResponse(offer)
+			resp.Available = append(resp.Available, item)
+		}
+	}
+
+	// 可用券按单独使用的优惠从大到小排列，便于用户挑选
+	sort.SliceStable(resp.Available, func(i, j int) bool {
+		return offerValue(resp.Available[i].Offer) > offerValue(resp.Available[j].Offer)
+	})
+	return resp
+}
+
+// checkoutMessage 返回规则未满足等情况下展示给用户的文案。
+func checkoutMessage(e *couponEvaluation) string {
+	switch e.outcome {
+	case CouponOutcomeRuleFalse:
+		if e.template != nil && e.template.RuleHint != "" {
+			return e.template.RuleHint
+		}
+		return checkoutMessageNotApplicable
+	case CouponOutcomeStoreNotInCart:
+		return checkoutMessageStoreOnly
+	case CouponOutcomeExpired:
+		return checkoutMessageExpired
+	case CouponOutcomeFrozen:
+		return checkoutMessageFrozen
+	default:
+		return checkoutMessageUnavailable
+	}
+}
+
+// shortfallOf 返回距离门槛还差的金额或件数，策略没有门槛或无法计算时返回零值。
+func shortfallOf(e *couponEvaluation) domain.Shortfall {
+	s, ok := e.candidate.strategy.(domain.ShortfallStrategy)
+	if !ok {
+		return domain.Shortfall{}
+	}
+	shortfall, err := s.Shortfall(e.fact, e.template)
+	if err != nil {
+		return domain.Shortfall{}
+	}
+	return shortfall
+}
+
+// describeShortfall 将还差的金额或件数格式化为展示文案，如 ¥23、1件。
+func describeShortfall(s domain.Shortfall) string {
+	if s.Amount > 0 {
+		return formatYuan(s.Amount)
+	}
+	return fmt.Sprintf("%d件", s.Quantity)
+}
+
+func offerValue(offer *DiscountApplicationResponse) int64 {
+	if offer == nil {
+		return 0
+	}
+	if offer.Reward != nil {
+		return offer.Amount + offer.Reward.MonetaryValue
+	}
+	return offer.Amount
+}
+
+// formatYuan 将以分为单位的金额格式化为展示用的元，整数金额不显示小数，如 ¥23、¥23.50。
+func formatYuan(cents int64) string {
+	if cents%100 == 0 {
+		return fmt.Sprintf("¥%d", cents/100)
+	}
+	return fmt.Sprintf("¥%d.%02d", cents/100, cents%100)
+}
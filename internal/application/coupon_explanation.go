@@ -0,0 +1,131 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// CouponOutcome 描述一张券在一次优惠计算中的结果。
+type CouponOutcome string
+
+const (
+	CouponOutcomeApplied          CouponOutcome = "APPLIED"              // 被最优组合使用
+	CouponOutcomeApplicable       CouponOutcome = "APPLICABLE"           // 规则满足, 可以使用
+	CouponOutcomeLostToBetter     CouponOutcome = "LOST_TO_BETTER_OFFER" // 单独可用, 但其它组合更优或与之不能叠加
+	CouponOutcomeBelowThreshold   CouponOutcome = "BELOW_THRESHOLD"      // 规则满足, 但当前购物车算不出优惠（如未达门槛）
+	CouponOutcomeExpired          CouponOutcome = "EXPIRED"              // 券已过期
+	CouponOutcomeFrozen           CouponOutcome = "FROZEN"               // 券已被其它订单冻结
+	CouponOutcomeUsed             CouponOutcome = "USED"                 // 券已使用
+	CouponOutcomeTemplateMissing  CouponOutcome = "TEMPLATE_MISSING"     // 券关联的模板不存在
+	CouponOutcomeStoreNotInCart   CouponOutcome = "STORE_NOT_IN_CART"    // 店铺券所属店铺的商品不在购物车中
+	CouponOutcomeRuleFalse        CouponOutcome = "RULE_FALSE"           // 规则不满足
+	CouponOutcomeRuleError        CouponOutcome = "RULE_ERROR"           // 规则执行出错
+	CouponOutcomeUnsupported      CouponOutcome = "UNSUPPORTED_DISCOUNT" // 不支持的优惠类型
+	CouponOutcomeCalculationError CouponOutcome = "CALCULATION_ERROR"    // 优惠计算出错, 通常是参数不合法
+)
+
+// couponEvaluation 记录一张券在当前Fact下的评估过程。
+// 规则满足时 candidate 非空、outcome 为空，最终结果要等择优之后才能确定。
+type couponEvaluation struct {
+	coupon    *domain.UserCoupon
+	template  *domain.PromotionTemplate
+	fact      domain.Fact // 评估规则时使用的Fact, 店铺券是该店铺的子Fact
+	candidate *offerCandidate
+	outcome   CouponOutcome
+	reason    string
+}
+
+// couponStatusOutcome 根据券自身的状态判断它是否可用，可用时返回空的结果。
+func couponStatusOutcome(c *domain.UserCoupon) (CouponOutcome, string) {
+	switch c.Status {
+	case domain.StatusFrozen:
+		return CouponOutcomeFrozen, "coupon is frozen by a pending order"
+	case domain.StatusUsed:
+		return CouponOutcomeUsed, "coupon has already been used"
+	case domain.StatusExpired:
+		return CouponOutcomeExpired, fmt.Sprintf("coupon expired at %s", c.ExpiryDate.Format(time.RFC3339))
+	}
+	if !c.IsAvailable() {
+		return CouponOutcomeExpired, fmt.Sprintf("coupon expired at %s", c.ExpiryDate.Format(time.RFC3339))
+	}
+	return "", ""
+}
+
+// candidatesOf 返回规则满足、可以参与择优的券。
+func candidatesOf(evaluations []*couponEvaluation) []*offerCandidate {
+	candidates := make([]*offerCandidate, 0, len(evaluations))
+	for _, e := range evaluations {
+		if e.candidate != nil {
+			candidates = append(candidates, e.candidate)
+		}
+	}
+	return candidates
+}
+
+// toApplicableCouponResponses 将规则满足的券转换为DTO
+func toApplicableCouponResponses(evaluations []*couponEvaluation) []*UserCouponResponse {
+	resp := make([]*UserCouponResponse, 0, len(evaluations))
+	for _, c := range candidatesOf(evaluations) {
+		resp = append(resp, toUserCouponResponse(c.coupon))
+	}
+	return resp
+}
+
+// explainApplicable 说明每张券在当前购物车下可用或不可用的原因。
+func explainApplicable(evaluations []*couponEvaluation) []*CouponExplanationResponse {
+	resp := make([]*CouponExplanationResponse, 0, len(evaluations))
+	for _, e := range evaluations {
+		if e.candidate != nil {
+			resp = append(resp, toCouponExplanationResponse(e, CouponOutcomeApplicable, "rule is satisfied by the current cart", nil))
+			continue
+		}
+		resp = append(resp, toCouponExplanationResponse(e, e.outcome, e.reason, nil))
+	}
+	return resp
+}
+
+// explainBestOffer 说明每张券是否被最优组合使用，以及未被使用的原因。
+// 未被使用的券会单独计算一次，以区分“算不出优惠”和“被更优的组合淘汰”。
+func explainBestOffer(evaluations []*couponEvaluation, plans ...*offerPlan) []*CouponExplanationResponse {
+	applied := make(map[*offerCandidate]*domain.DiscountApplication)
+	for _, p := range plans {
+		if p == nil {
+			continue
+		}
+		for _, a := range p.applied {
+			applied[a.candidate] = a.offer
+		}
+	}
+
+	resp := make([]*CouponExplanationResponse, 0, len(evaluations))
+	for _, e := range evaluations {
+		if e.candidate == nil {
+			resp = append(resp, toCouponExplanationResponse(e, e.outcome, e.reason, nil))
+			continue
+		}
+
+		if offer, ok := applied[e.candidate]; ok {
+			resp = append(resp, toCouponExplanationResponse(e, CouponOutcomeApplied,
+				fmt.Sprintf("applied in the best combination, discount %d", offer.Amount), offer))
+			continue
+		}
+
+		offer, err := e.candidate.strategy.Calculate(e.fact, e.template)
+		switch {
+		case err != nil:
+			resp = append(resp, toCouponExplanationResponse(e, CouponOutcomeCalculationError, err.Error(), nil))
+		case offer == nil || offer.Value() <= 0:
+			reason := "no discount for the current cart"
+			if offer != nil && offer.Description != "" {
+				reason = fmt.Sprintf("%s: %s", reason, offer.Description)
+			}
+			resp = append(resp, toCouponExplanationResponse(e, CouponOutcomeBelowThreshold, reason, nil))
+		default:
+			resp = append(resp, toCouponExplanationResponse(e, CouponOutcomeLostToBetter,
+				fmt.Sprintf("worth %d on its own, but a better combination was chosen", offer.Value()), offer))
+		}
+	}
+	return resp
+}
@@ -0,0 +1,98 @@
+// internal/application/coupon_explanation_test.go
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestExplainBestOffer_ReportsOutcomePerCoupon(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	winner := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 2000}`, 100, true)
+	loser := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, true)
+	below := newCandidate(t, 3, domain.DiscountTypeFixedAmount, `{"threshold": 20000, "amount": 5000}`, 100, true)
+	frozen := &domain.UserCoupon{ID: 4, TemplateID: 4, Status: domain.StatusFrozen, ExpiryDate: time.Now().Add(time.Hour)}
+
+	evaluations := []*couponEvaluation{
+		{coupon: winner.coupon, template: winner.template, fact: fact, candidate: winner},
+		{coupon: loser.coupon, template: loser.template, fact: fact, candidate: loser},
+		{coupon: below.coupon, template: below.template, fact: fact, candidate: below},
+	}
+	outcome, reason := couponStatusOutcome(frozen)
+	evaluations = append(evaluations, &couponEvaluation{coupon: frozen, outcome: outcome, reason: reason})
+
+	plan := (&offerOptimizer{}).optimizeCart(fact, candidatesOf(evaluations))
+	explanations := explainBestOffer(evaluations, plan)
+
+	expected := []CouponOutcome{CouponOutcomeApplied, CouponOutcomeLostToBetter, CouponOutcomeBelowThreshold, CouponOutcomeFrozen}
+	for i, e := range explanations {
+		if e.Outcome != expected[i] {
+			t.Errorf("coupon %d: expected outcome %s; got %s (%s)", i+1, expected[i], e.Outcome, e.Reason)
+		}
+	}
+}
+
+func TestCheckoutCoupons_UnavailableCouponsCarryUserFacingReason(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 7700, Quantity: 1}},
+		TotalAmount: 7700,
+	}
+	usable := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 5000, "amount": 500}`, 100, false)
+	short := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 10000, "amount": 2000}`, 100, false)
+	vipOnly := &domain.PromotionTemplate{ID: 3, RuleHint: "仅限VIP"}
+
+	evaluations := []*couponEvaluation{
+		{coupon: usable.coupon, template: usable.template, fact: fact, candidate: usable},
+		{coupon: short.coupon, template: short.template, fact: fact, candidate: short},
+		{coupon: &domain.UserCoupon{ID: 3, TemplateID: 3}, template: vipOnly, outcome: CouponOutcomeRuleFalse},
+	}
+
+	resp := toCheckoutCouponsResponse(evaluations)
+	if len(resp.Available) != 1 || resp.Available[0].ID != 1 {
+		t.Fatalf("expected coupon 1 to be available; got %+v", resp.Available)
+	}
+	if len(resp.Unavailable) != 2 {
+		t.Fatalf("expected 2 unavailable coupons; got %d", len(resp.Unavailable))
+	}
+	if got := resp.Unavailable[0].ReasonMessage; got != "差¥23可用" {
+		t.Errorf("expected shortfall message; got %q", got)
+	}
+	if got := resp.Unavailable[0].Shortfall; got == nil || got.Amount != 2300 {
+		t.Errorf("expected a shortfall of 2300; got %+v", got)
+	}
+	if got := resp.Unavailable[1].ReasonMessage; got != "仅限VIP" {
+		t.Errorf("expected rule hint; got %q", got)
+	}
+}
+
+func TestUpsellHints_SmallestShortfallFirst(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 7700, Quantity: 1}},
+		TotalAmount: 7700,
+	}
+	far := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 20000, "amount": 5000}`, 100, false)
+	near := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 10000, "amount": 2000}`, 100, false)
+	near.template.Name = "满100减20"
+	bundle := newCandidate(t, 3, domain.DiscountTypeBundlePrice, `{"bundle_size": 3, "bundle_price": 9900}`, 100, false)
+
+	var evaluations []*couponEvaluation
+	for _, c := range []*offerCandidate{far, near, bundle} {
+		evaluations = append(evaluations, &couponEvaluation{coupon: c.coupon, template: c.template, fact: fact, candidate: c})
+	}
+
+	hints := upsellHints(evaluations)
+	if len(hints) != 3 {
+		t.Fatalf("expected 3 hints; got %d", len(hints))
+	}
+	if hints[0].TemplateID != 2 || hints[0].Message != "再买¥23可享「满100减20」" {
+		t.Errorf("expected the nearest threshold first; got %+v", hints[0])
+	}
+	if hints[2].Shortfall == nil || hints[2].Shortfall.Quantity != 2 {
+		t.Errorf("expected bundle to need 2 more items; got %+v", hints[2])
+	}
+}
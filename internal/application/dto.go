@@ -2,6 +2,7 @@ package application
 
 import (
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/discount"
 	"time"
 )
 
@@ -10,12 +11,16 @@ type CreateTemplateRequest struct {
 	Name               string    `json:"name"`
 	Description        string    `json:"description"`
 	PromotionType      string    `json:"promotion_type"`
+	StoreID            int64     `json:"store_id"`      // 所属店铺ID, 店铺券必填, 0 表示平台级促销
+	RuleLanguage       string    `json:"rule_language"` // 规则语言, 'CEL' 或 'JSON'（不区分大小写）, 为空时为 'CEL'
 	RuleDefinition     string    `json:"rule_definition"`
+	RuleHint           string    `json:"rule_hint"` // 面向用户的规则说明，规则不满足时展示在结算页
 	DiscountType       string    `json:"discount_type"`
 	DiscountProperties string    `json:"discount_properties"`
 	StartDate          time.Time `json:"start_date"`
 	EndDate            time.Time `json:"end_date"`
 	IsExclusive        bool      `json:"is_exclusive"`
+	IsAutomatic        bool      `json:"is_automatic"` // 自动促销无需领券，对所有满足规则的购物车生效
 	Priority           int       `json:"priority"`
 }
 
@@ -25,12 +30,15 @@ type UpdateTemplateRequest struct {
 	TemplateGroupID    string    `json:"template_group_id"`
 	Name               string    `json:"name"`
 	Description        string    `json:"description"`
+	RuleLanguage       string    `json:"rule_language"` // 规则语言, 'CEL' 或 'JSON'（不区分大小写）, 为空时沿用当前版本的语言
 	RuleDefinition     string    `json:"rule_definition"`
+	RuleHint           string    `json:"rule_hint"` // 面向用户的规则说明，规则不满足时展示在结算页
 	DiscountType       string    `json:"discount_type"`
 	DiscountProperties string    `json:"discount_properties"`
 	StartDate          time.Time `json:"start_date"`
 	EndDate            time.Time `json:"end_date"`
 	IsExclusive        bool      `json:"is_exclusive"`
+	IsAutomatic        bool      `json:"is_automatic"` // 自动促销无需领券，对所有满足规则的购物车生效
 	Priority           int       `json:"priority"`
 }
 
@@ -43,12 +51,16 @@ type TemplateResponse struct {
 	Name               string    `json:"name"`
 	Description        string    `json:"description"`
 	PromotionType      string    `json:"promotion_type"`
+	StoreID            int64     `json:"store_id"`
+	RuleLanguage       string    `json:"rule_language"`
 	RuleDefinition     string    `json:"rule_definition"`
+	RuleHint           string    `json:"rule_hint"`
 	DiscountType       string    `json:"discount_type"`
 	DiscountProperties string    `json:"discount_properties"`
 	StartDate          time.Time `json:"start_date"`
 	EndDate            time.Time `json:"end_date"`
 	IsExclusive        bool      `json:"is_exclusive"`
+	IsAutomatic        bool      `json:"is_automatic"`
 	Priority           int       `json:"priority"`
 	IsActive           bool      `json:"is_active"`
 	CreatedAt          time.Time `json:"created_at"`
@@ -82,9 +94,183 @@ type UserCouponResponse struct {
 // DiscountApplicationResponse 是优惠计算结果的DTO。
 // 用于向调用方展示计算出的优惠详情。
 type DiscountApplicationResponse struct {
-	Amount       int64  `json:"amount"`        // 优惠的总金额
-	StrategyName string `json:"strategy_name"` // 应用的策略名称
-	Description  string `json:"description"`   // 优惠的描述
+	Amount       int64                     `json:"amount"`                // 优惠的总金额
+	StrategyName string                    `json:"strategy_name"`         // 应用的策略名称
+	Description  string                    `json:"description"`           // 优惠的描述
+	Layer        domain.DiscountLayer      `json:"layer,omitempty"`       // 优惠作用的层级（商品或运费）
+	Reward       *RewardResponse           `json:"reward,omitempty"`      // 非金额类权益（积分、返现、赠品）
+	Allocations  []*LineAllocationResponse `json:"allocations,omitempty"` // 优惠在各购物车行上的分摊明细
+}
+
+// RewardResponse 是非金额类权益的DTO。
+type RewardResponse struct {
+	Type          domain.RewardType `json:"type"`
+	Points        int64             `json:"points,omitempty"`        // 赠送的积分数
+	Cashback      int64             `json:"cashback,omitempty"`      // 返现金额（单位：分）
+	GiftSKU       string            `json:"gift_sku,omitempty"`      // 赠品SKU
+	GiftQuantity  int32             `json:"gift_quantity,omitempty"` // 赠品数量
+	MonetaryValue int64             `json:"monetary_value"`          // 折算的等价金额（单位：分）
+}
+
+// BestOfferResponse 是最优优惠计算结果的DTO。
+// 商品优惠与运费优惠分属不同层级、互不竞争：顶层字段是商品层的最优券组合，
+// Shipping 是运费层的最优券组合，没有可用的运费优惠时为空。
+type BestOfferResponse struct {
+	OfferPlanResponse
+	Shipping     *OfferPlanResponse           `json:"shipping,omitempty"`
+	UpsellHints  []*UpsellHintResponse        `json:"upsell_hints,omitempty"` // 差一点就能使用的优惠，用于购物车凑单提示
+	Explanations []*CouponExplanationResponse `json:"explanations,omitempty"` // 仅在 explain 模式下返回
+}
+
+// UpsellHintResponse 是一条凑单提示，告诉用户再买多少就能使用某张券或某个自动促销。
+type UpsellHintResponse struct {
+	CouponCode   string             `json:"coupon_code,omitempty"`
+	TemplateID   int64              `json:"template_id"`
+	TemplateName string             `json:"template_name"`
+	IsAutomatic  bool               `json:"is_automatic"`
+	Shortfall    *ShortfallResponse `json:"shortfall"`
+	Message      string             `json:"message"` // 如 "再买¥23可享「满100减20」"
+}
+
+// QuoteOfferRequest 定义了按用户手动选择的券计算优惠的请求。
+type QuoteOfferRequest struct {
+	Fact        domain.Fact `json:"fact"`
+	CouponCodes []string    `json:"coupon_codes"`
+}
+
+// OfferOptions 控制最优优惠计算的可选行为。
+type OfferOptions struct {
+	Explain   bool      // 是否说明用户持有的每张券被使用或未被使用的原因
+	Objective Objective // 择优目标，为空时使用渠道配置的目标，渠道未配置时优惠最大化
+}
+
+// CouponExplanationResponse 说明一张券在本次计算中的结果及原因，供客服排查“为什么我的券没用上”。
+type CouponExplanationResponse struct {
+	CouponCode   string                       `json:"coupon_code"`
+	TemplateID   int64                        `json:"template_id"`
+	TemplateName string                       `json:"template_name,omitempty"`
+	Status       domain.UserCouponStatus      `json:"status"`
+	Outcome      CouponOutcome                `json:"outcome"`
+	Reason       string                       `json:"reason"`
+	Offer        *DiscountApplicationResponse `json:"offer,omitempty"` // 被使用时是实际优惠, 被淘汰时是单独使用的优惠
+}
+
+// ApplicableCouponsResponse 是 explain 模式下可用优惠券列表的DTO。
+type ApplicableCouponsResponse struct {
+	Coupons      []*UserCouponResponse        `json:"coupons"`
+	Explanations []*CouponExplanationResponse `json:"explanations"`
+}
+
+// OfferPlanResponse 是一个层级内最优券组合的DTO。
+// 顶层字段是整个组合的汇总，Coupons 按计算顺序列出组合中的每张券及其各自的优惠。
+type OfferPlanResponse struct {
+	DiscountApplicationResponse
+	Coupons []*AppliedCouponResponse `json:"coupons,omitempty"`
+}
+
+// AppliedCouponResponse 描述组合中的一张券及其在剩余应付金额上计算出的优惠。
+// 自动促销没有券码，IsAutomatic 为 true。
+type AppliedCouponResponse struct {
+	CouponCode   string                       `json:"coupon_code,omitempty"`
+	IsAutomatic  bool                         `json:"is_automatic"`
+	TemplateID   int64                        `json:"template_id"`
+	TemplateName string                       `json:"template_name"`
+	Priority     int                          `json:"priority"`
+	IsExclusive  bool                         `json:"is_exclusive"`
+	Offer        *DiscountApplicationResponse `json:"offer"`
+}
+
+// LineAllocationResponse 是优惠分摊到单个购物车行的DTO。
+// 订单服务可以据此计算部分退货时每一行应退的金额。
+type LineAllocationResponse struct {
+	LineIndex int    `json:"line_index"` // 对应请求中 Items 的下标
+	SKU       string `json:"sku"`
+	Amount    int64  `json:"amount"` // 分摊到该行的优惠金额（单位：分）
+}
+
+// DiscountTypeResponse 描述一种支持的优惠类型及其参数结构，供管理后台渲染模板表单。
+type DiscountTypeResponse struct {
+	Type       domain.DiscountType       `json:"type"`
+	Name       string                    `json:"name"`
+	Layer      domain.DiscountLayer      `json:"layer"`
+	Properties []*PropertySchemaResponse `json:"properties"` // DiscountProperties 的字段描述
+}
+
+// PropertySchemaResponse 描述 DiscountProperties 中的一个字段。
+type PropertySchemaResponse struct {
+	Name        string                    `json:"name,omitempty"`
+	Type        string                    `json:"type"` // integer / string / boolean / array / object
+	Description string                    `json:"description,omitempty"`
+	Items       *PropertySchemaResponse   `json:"items,omitempty"`      // 数组元素的结构
+	Properties  []*PropertySchemaResponse `json:"properties,omitempty"` // 对象的字段
+}
+
+// ConvertRuleRequest 定义了把 JSON 规则转换为 CEL 表达式的请求。
+type ConvertRuleRequest struct {
+	RuleDefinition string `json:"rule_definition"` // RuleGroup/Condition 格式的 JSON 规则
+}
+
+// ConvertRuleResponse 是转换后的规则，可以直接作为模板的 rule_language 和 rule_definition 保存。
+type ConvertRuleResponse struct {
+	RuleLanguage   string `json:"rule_language"`
+	RuleDefinition string `json:"rule_definition"`
+}
+
+// RuleMigrationResponse 汇总了一次 JSON 规则到 CEL 的迁移结果。
+type RuleMigrationResponse struct {
+	Applied   bool                         `json:"applied"`   // 为 false 时只是预览，没有写入数据库
+	Converted int                          `json:"converted"` // 转换成功的模板数
+	Failed    int                          `json:"failed"`    // 转换失败、保持原样的模板数
+	Templates []*RuleMigrationItemResponse `json:"templates"`
+}
+
+// RuleMigrationItemResponse 是一个模板的迁移结果。
+type RuleMigrationItemResponse struct {
+	TemplateID    int64  `json:"template_id"`
+	NewTemplateID int64  `json:"new_template_id,omitempty"` // 迁移后新版本的模板ID，仅在写入后返回
+	TemplateName  string `json:"template_name"`
+	JSONRule      string `json:"json_rule"`
+	CELExpression string `json:"cel_expression,omitempty"`
+	Error         string `json:"error,omitempty"` // 转换失败的原因，指明出错条件在规则树中的位置
+}
+
+// StackingPolicyRequest 定义了创建或覆盖一种促销类型叠加规则的请求。
+type StackingPolicyRequest struct {
+	PromotionType string   `json:"promotion_type"`
+	MaxCount      int      `json:"max_count"`      // 同类型最多可同时使用的张数, 0 表示不限制
+	StackableWith []string `json:"stackable_with"` // 可以与之叠加的其它促销类型, "*" 表示任意类型
+}
+
+// StackingPolicyResponse 是促销类型叠加规则的视图。
+type StackingPolicyResponse struct {
+	PromotionType string    `json:"promotion_type"`
+	MaxCount      int       `json:"max_count"`
+	StackableWith []string  `json:"stackable_with"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ShortfallResponse 描述距离优惠门槛还差多少，结算页和凑单提示共用，金额与件数按策略类型二选一。
+type ShortfallResponse struct {
+	Amount   int64 `json:"amount,omitempty"`   // 还差的金额（单位：分）
+	Quantity int32 `json:"quantity,omitempty"` // 还差的件数
+}
+
+// CheckoutCouponsResponse 是结算页“可用/不可用”两个优惠券列表的DTO。
+type CheckoutCouponsResponse struct {
+	Available   []*CheckoutCouponResponse `json:"available"`
+	Unavailable []*CheckoutCouponResponse `json:"unavailable"`
+}
+
+// CheckoutCouponResponse 是结算页中的一张券。
+// 可用券带有单独使用时的优惠；不可用券带有原因码和可以直接展示给用户的文案。
+type CheckoutCouponResponse struct {
+	UserCouponResponse
+	TemplateName        string                       `json:"template_name,omitempty"`
+	TemplateDescription string                       `json:"template_description,omitempty"`
+	Offer               *DiscountApplicationResponse `json:"offer,omitempty"`
+	ReasonCode          CouponOutcome                `json:"reason_code,omitempty"`
+	ReasonMessage       string                       `json:"reason_message,omitempty"` // 如 "差¥23可用"、"仅限VIP"
+	Shortfall           *ShortfallResponse           `json:"shortfall,omitempty"`      // 距离门槛还差多少，仅未达门槛时返回
 }
 
 // --- Mapper Functions ---
@@ -101,12 +287,16 @@ func toTemplateResponse(d *domain.PromotionTemplate) *TemplateResponse {
 		Name:               d.Name,
 		Description:        d.Description,
 		PromotionType:      d.PromotionType,
+		StoreID:            d.StoreID,
+		RuleLanguage:       string(d.Rule().EffectiveLanguage()),
 		RuleDefinition:     d.RuleDefinition,
+		RuleHint:           d.RuleHint,
 		DiscountType:       string(d.DiscountType),
 		DiscountProperties: d.DiscountProperties,
 		StartDate:          d.StartDate,
 		EndDate:            d.EndDate,
 		IsExclusive:        d.IsExclusive,
+		IsAutomatic:        d.IsAutomatic,
 		Priority:           d.Priority,
 		IsActive:           d.IsActive,
 		CreatedAt:          d.CreatedAt,
@@ -136,9 +326,138 @@ func toDiscountApplicationResponse(d *domain.DiscountApplication) *DiscountAppli
 	if d == nil {
 		return nil
 	}
-	return &DiscountApplicationResponse{
+	resp := &DiscountApplicationResponse{
 		Amount:       d.Amount,
 		StrategyName: d.StrategyName,
 		Description:  d.Description,
+		Layer:        d.Layer,
+	}
+	if d.Reward != nil {
+		resp.Reward = &RewardResponse{
+			Type:          d.Reward.Type,
+			Points:        d.Reward.Points,
+			Cashback:      d.Reward.Cashback,
+			GiftSKU:       d.Reward.GiftSKU,
+			GiftQuantity:  d.Reward.GiftQuantity,
+			MonetaryValue: d.Reward.MonetaryValue,
+		}
+	}
+	for _, a := range d.Allocations {
+		resp.Allocations = append(resp.Allocations, &LineAllocationResponse{
+			LineIndex: a.LineIndex,
+			SKU:       a.SKU,
+			Amount:    a.Amount,
+		})
+	}
+	return resp
+}
+
+// toBestOfferResponse 将各层级的最优券组合组装为DTO，商品层没有可用优惠时返回“无可用优惠”
+func toBestOfferResponse(goods, shipping *offerPlan) *BestOfferResponse {
+	resp := &BestOfferResponse{Shipping: toOfferPlanResponse(shipping, domain.DiscountLayerShipping)}
+	if goods == nil {
+		resp.DiscountApplicationResponse = *toDiscountApplicationResponse(
+			&domain.DiscountApplication{Amount: 0, Description: "无可用优惠", Layer: domain.DiscountLayerGoods})
+		return resp
+	}
+	resp.OfferPlanResponse = *toOfferPlanResponse(goods, domain.DiscountLayerGoods)
+	return resp
+}
+
+// toOfferPlanResponse 将券组合转换为DTO
+func toOfferPlanResponse(p *offerPlan, layer domain.DiscountLayer) *OfferPlanResponse {
+	if p == nil {
+		return nil
+	}
+	resp := &OfferPlanResponse{DiscountApplicationResponse: *toDiscountApplicationResponse(p.summary(layer))}
+	for _, a := range p.applied {
+		applied := &AppliedCouponResponse{
+			TemplateID:   a.candidate.template.ID,
+			TemplateName: a.candidate.template.Name,
+			Priority:     a.candidate.template.Priority,
+			IsExclusive:  a.candidate.template.IsExclusive,
+			IsAutomatic:  a.candidate.coupon == nil,
+			Offer:        toDiscountApplicationResponse(a.offer),
+		}
+		if a.candidate.coupon != nil {
+			applied.CouponCode = a.candidate.coupon.CouponCode
+		}
+		resp.Coupons = append(resp.Coupons, applied)
+	}
+	return resp
+}
+
+// toShortfallResponse 将门槛差额转换为DTO，已达到门槛时返回 nil
+func toShortfallResponse(s domain.Shortfall) *ShortfallResponse {
+	if s.IsZero() {
+		return nil
+	}
+	return &ShortfallResponse{Amount: s.Amount, Quantity: s.Quantity}
+}
+
+// toStackingPolicyResponse 将领域对象转换为DTO
+func toStackingPolicyResponse(d *domain.StackingPolicy) *StackingPolicyResponse {
+	if d == nil {
+		return nil
+	}
+	stackableWith := d.StackableWith
+	if stackableWith == nil {
+		stackableWith = []string{}
+	}
+	return &StackingPolicyResponse{
+		PromotionType: d.PromotionType,
+		MaxCount:      d.MaxCount,
+		StackableWith: stackableWith,
+		UpdatedAt:     d.UpdatedAt,
+	}
+}
+
+// toCouponExplanationResponse 将券的评估结果转换为DTO
+func toCouponExplanationResponse(e *couponEvaluation, outcome CouponOutcome, reason string, offer *domain.DiscountApplication) *CouponExplanationResponse {
+	resp := &CouponExplanationResponse{
+		CouponCode: e.coupon.CouponCode,
+		TemplateID: e.coupon.TemplateID,
+		Status:     e.coupon.Status,
+		Outcome:    outcome,
+		Reason:     reason,
+		Offer:      toDiscountApplicationResponse(offer),
+	}
+	if e.template != nil {
+		resp.TemplateName = e.template.Name
+	}
+	return resp
+}
+
+// toDiscountTypeResponse 将策略注册表中的描述转换为DTO
+func toDiscountTypeResponse(d discount.StrategyDescriptor) *DiscountTypeResponse {
+	return &DiscountTypeResponse{
+		Type:       d.Type,
+		Name:       d.Name,
+		Layer:      d.Layer,
+		Properties: toPropertySchemaResponses(d.Properties),
+	}
+}
+
+func toPropertySchemaResponses(schemas []discount.PropertySchema) []*PropertySchemaResponse {
+	if len(schemas) == 0 {
+		return nil
+	}
+	resp := make([]*PropertySchemaResponse, 0, len(schemas))
+	for i := range schemas {
+		resp = append(resp, toPropertySchemaResponse(&schemas[i]))
+	}
+	return resp
+}
+
+func toPropertySchemaResponse(s *discount.PropertySchema) *PropertySchemaResponse {
+	if s == nil {
+		return nil
+	}
+	return &PropertySchemaResponse{
+		Name:        s.Name,
+		Type:        s.Type,
+		Description: s.Description,
+		Items:       toPropertySchemaResponse(s.Items),
+		Properties:  toPropertySchemaResponses(s.Properties),
 	}
 }
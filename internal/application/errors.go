@@ -0,0 +1,40 @@
+package application
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError 描述请求中某个字段上的一个问题。
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError 汇总了一次请求中发现的所有校验问题。
+// 接口层据此返回 4xx 和结构化的问题列表，而不是只返回第一个错误。
+type ValidationError struct {
+	Message  string        `json:"error"`
+	Problems []*FieldError `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		parts = append(parts, fmt.Sprintf("%s: %s", p.Field, p.Message))
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(parts, "; "))
+}
+
+// add 记录一个字段问题。
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// errOrNil 在没有发现任何问题时返回 nil，避免返回一个非空的空错误。
+func (e *ValidationError) errOrNil() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,192 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Objective 是最优优惠计算的择优目标。
+type Objective string
+
+const (
+	// ObjectiveMaxDiscount 让本单优惠最大化，是默认目标。
+	ObjectiveMaxDiscount Objective = "MAX_DISCOUNT"
+	// ObjectiveExpiringSoon 在与最大优惠相差不超过容忍金额的组合中，优先使用最快过期的券，把长期有效的大额券留到以后。
+	ObjectiveExpiringSoon Objective = "EXPIRING_SOON"
+	// ObjectivePlatformFunded 在与最大优惠相差不超过容忍金额的组合中，优先使用平台出资的券，少用商家出资的店铺券。
+	ObjectivePlatformFunded Objective = "PLATFORM_FUNDED"
+)
+
+// DefaultObjectiveTolerance 是偏好类目标默认允许让出的优惠金额（单位：分）。
+const DefaultObjectiveTolerance int64 = 500
+
+// offerObjective 从若干个可行的券组合中选出一个，plans 中每个组合的价值都大于 0。
+type offerObjective interface {
+	// choose 在价值不低于“最大价值 - tolerance”的组合中选出一个
+	choose(plans []*offerPlan, tolerance int64) *offerPlan
+	// tolerance 返回该目标在一个优惠层级的整单上最多允许让出的优惠金额
+	tolerance() int64
+}
+
+// objectives 是内置择优目标的注册表，参数为偏好类目标允许让出的优惠金额。
+var objectives = map[Objective]func(tolerance int64) offerObjective{
+	ObjectiveMaxDiscount: func(int64) offerObjective {
+		return &toleranceObjective{}
+	},
+	ObjectiveExpiringSoon: func(tolerance int64) offerObjective {
+		return &toleranceObjective{allowance: tolerance, prefer: compareExpiry}
+	},
+	ObjectivePlatformFunded: func(tolerance int64) offerObjective {
+		return &toleranceObjective{allowance: tolerance, prefer: comparePlatformFunded}
+	},
+}
+
+// newObjective 按名称创建择优目标。
+func newObjective(name Objective, tolerance int64) (offerObjective, error) {
+	factory, ok := objectives[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported objective: %s", name)
+	}
+	return factory(tolerance), nil
+}
+
+// toleranceObjective 先找出最大价值，再在价值不低于“最大价值 - tolerance”的组合中按 prefer 选择，
+// prefer 相同时价值更大者优先，仍相同时按 tieBreak 决定。
+// allowance 为 0 且没有 prefer 时即为优惠最大化。
+type toleranceObjective struct {
+	allowance int64                     // 整单最多允许让出的优惠金额
+	prefer    func(a, b *offerPlan) int // a 更优时返回负数
+}
+
+func (o *toleranceObjective) tolerance() int64 {
+	return o.allowance
+}
+
+func (o *toleranceObjective) choose(plans []*offerPlan, tolerance int64) *offerPlan {
+	if len(plans) == 0 {
+		return nil
+	}
+	maxValue := maxPlanValue(plans)
+
+	eligible := make([]*offerPlan, 0, len(plans))
+	for _, p := range plans {
+		if p.value >= maxValue-tolerance {
+			eligible = append(eligible, p)
+		}
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		a, b := eligible[i], eligible[j]
+		if o.prefer != nil {
+			if c := o.prefer(a, b); c != 0 {
+				return c < 0
+			}
+		}
+		if a.value != b.value {
+			return a.value > b.value
+		}
+		return tieBreak(a, b) < 0
+	})
+	return eligible[0]
+}
+
+// maxPlanValue 返回组合中的最大价值。
+func maxPlanValue(plans []*offerPlan) int64 {
+	var maxValue int64
+	for _, p := range plans {
+		if p.value > maxValue {
+			maxValue = p.value
+		}
+	}
+	return maxValue
+}
+
+// tieBreak 在价值相同的组合之间做确定性的选择，依次比较：
+// 最早过期时间更早者、最高优先级更高者、平台出资金额更多者、用券更少者，最后比较首张券的券ID。
+func tieBreak(a, b *offerPlan) int {
+	if c := compareExpiry(a, b); c != 0 {
+		return c
+	}
+	if pa, pb := a.maxPriority(), b.maxPriority(); pa != pb {
+		if pa > pb {
+			return -1
+		}
+		return 1
+	}
+	if c := comparePlatformFunded(a, b); c != 0 {
+		return c
+	}
+	if len(a.applied) != len(b.applied) {
+		return len(a.applied) - len(b.applied)
+	}
+	ka, kb := candidateKey(a.applied[0].candidate), candidateKey(b.applied[0].candidate)
+	switch {
+	case ka < kb:
+		return -1
+	case ka > kb:
+		return 1
+	}
+	return 0
+}
+
+// compareExpiry 让包含更早过期的券的组合优先，只有自动促销（没有过期时间）的组合排在最后。
+func compareExpiry(a, b *offerPlan) int {
+	ea, eb := a.earliestExpiry(), b.earliestExpiry()
+	switch {
+	case ea.Equal(eb):
+		return 0
+	case eb.IsZero():
+		return -1
+	case ea.IsZero():
+		return 1
+	case ea.Before(eb):
+		return -1
+	}
+	return 1
+}
+
+// comparePlatformFunded 让平台出资金额更多的组合优先。
+func comparePlatformFunded(a, b *offerPlan) int {
+	fa, fb := a.platformFunded(), b.platformFunded()
+	switch {
+	case fa > fb:
+		return -1
+	case fa < fb:
+		return 1
+	}
+	return 0
+}
+
+// earliestExpiry 返回组合中最早过期的券的过期时间，组合中没有用户券时返回零值。
+func (p *offerPlan) earliestExpiry() time.Time {
+	var earliest time.Time
+	for _, a := range p.applied {
+		if c := a.candidate.coupon; c != nil && !c.ExpiryDate.IsZero() &&
+			(earliest.IsZero() || c.ExpiryDate.Before(earliest)) {
+			earliest = c.ExpiryDate
+		}
+	}
+	return earliest
+}
+
+// maxPriority 返回组合中券的最高优先级。
+func (p *offerPlan) maxPriority() int {
+	highest := 0
+	for i, a := range p.applied {
+		if i == 0 || a.candidate.template.Priority > highest {
+			highest = a.candidate.template.Priority
+		}
+	}
+	return highest
+}
+
+// platformFunded 返回组合中由平台出资（非店铺券）的优惠价值。
+func (p *offerPlan) platformFunded() int64 {
+	var funded int64
+	for _, a := range p.applied {
+		if !a.candidate.template.IsStoreScoped() {
+			funded += a.offer.Value()
+		}
+	}
+	return funded
+}
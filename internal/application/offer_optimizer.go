@@ -0,0 +1,412 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// maxExhaustiveCandidates 是穷举券组合的候选数上限。
+// 可叠加的券超过这个数量时退化为按优先级贪心选择，避免组合数爆炸。
+const maxExhaustiveCandidates = 10
+
+// offerCandidate 是参与择优的一张券，以及计算它所需的模板和策略。
+type offerCandidate struct {
+	coupon   *domain.UserCoupon
+	template *domain.PromotionTemplate
+	strategy domain.DiscountStrategy
+}
+
+// appliedOffer 是组合中的一张券基于剩余应付金额计算出的结果。
+type appliedOffer struct {
+	candidate *offerCandidate
+	offer     *domain.DiscountApplication
+}
+
+// offerPlan 是一个券组合按优先级依次计算后的结果。
+type offerPlan struct {
+	applied []*appliedOffer
+	amount  int64 // 各券减少的应付金额之和
+	value   int64 // 含积分、返现等权益折算后的总价值
+}
+
+// offerOptimizer 负责在同一优惠层级的候选券中找出最优组合。
+// 互斥券（IsExclusive）只能单独使用，非互斥券之间按促销类型的叠加规则组合；
+// 组合内按 Priority 从高到低依次计算，每张券都基于前面的券扣减后的剩余应付金额。
+// 多个组合都可行时由 objective 决定取舍，未设置时优惠最大化。
+type offerOptimizer struct {
+	policies  domain.StackingPolicies
+	objective offerObjective
+}
+
+// optimizeCart 为多商家购物车求解最优组合。
+// 店铺券只作用于所属店铺的商品：先在每个店铺的子 Fact 上分别求出价值最高的若干个组合，
+// 再跨店铺联合挑选组合，叠加规则按整笔订单检查，因此 MaxCount 等限制对整笔订单生效，
+// 也不会因为店铺ID较小的店铺先选了互斥券而挡住其它店铺更划算的券；
+// 然后在扣除店铺优惠后的整单上叠加平台级促销。由于叠加规则可能使店铺券挡住更划算的平台促销，
+// 还会与只使用平台级促销的方案比较。
+// 所有整单方案最后只按择优目标取舍一次，因此无论购物车中有多少个店铺，偏好类目标让出的金额都不超过 tolerance。
+func (o *offerOptimizer) optimizeCart(fact domain.Fact, candidates []*offerCandidate) *offerPlan {
+	return o.choose(o.cartPlans(fact, candidates), o.tolerance())
+}
+
+// cartPlans 返回整单的可行方案：各店铺组合联合挑选后再叠加平台级促销的方案，以及只用平台级促销的方案。
+func (o *offerOptimizer) cartPlans(fact domain.Fact, candidates []*offerCandidate) []*offerPlan {
+	byStore := make(map[int64][]*offerCandidate)
+	storeIDs := make([]int64, 0)
+	platform := make([]*offerCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if !c.template.IsStoreScoped() {
+			platform = append(platform, c)
+			continue
+		}
+		if _, ok := byStore[c.template.StoreID]; !ok {
+			storeIDs = append(storeIDs, c.template.StoreID)
+		}
+		byStore[c.template.StoreID] = append(byStore[c.template.StoreID], c)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+
+	// storeCombos 是至少使用了一张店铺券的跨店铺组合，只保留价值最高的 maxStoreCombos 个
+	storeCombos := make([]*offerPlan, 0)
+	for _, storeID := range storeIDs {
+		sub, lineIndexes := fact.ForStore(storeID)
+		if len(lineIndexes) == 0 {
+			continue
+		}
+		storePlans := topPlans(o.plans(sub, nil, byStore[storeID]))
+		for _, plan := range storePlans {
+			plan.remapLines(lineIndexes)
+		}
+
+		next := append(append([]*offerPlan(nil), storeCombos...), storePlans...)
+		for _, combo := range storeCombos {
+			for _, plan := range storePlans {
+				if o.compatibleAll(combo.candidates(), plan.candidates()) {
+					next = append(next, combo.merge(plan))
+				}
+			}
+		}
+		storeCombos = topPlans(next)
+	}
+
+	plans := make([]*offerPlan, 0)
+	for _, combo := range append(storeCombos, nil) {
+		current := fact
+		if combo != nil {
+			for _, a := range combo.applied {
+				current = current.ApplyDiscount(a.offer)
+			}
+			plans = append(plans, combo)
+		}
+		for _, plan := range o.plans(current, combo.candidates(), platform) {
+			plans = append(plans, combo.merge(plan))
+		}
+	}
+	return plans
+}
+
+// maxStoreCombos 是跨店铺联合挑选时每一步保留的组合数上限，避免店铺多时组合数爆炸。
+const maxStoreCombos = 8
+
+// topPlans 按价值从高到低返回不重复的前 maxStoreCombos 个组合，价值相同时保持原有顺序。
+func topPlans(plans []*offerPlan) []*offerPlan {
+	sort.SliceStable(plans, func(i, j int) bool { return plans[i].value > plans[j].value })
+
+	result := make([]*offerPlan, 0, maxStoreCombos)
+	seen := make(map[string]bool)
+	for _, plan := range plans {
+		if key := plan.key(); !seen[key] {
+			seen[key] = true
+			result = append(result, plan)
+		}
+		if len(result) == maxStoreCombos {
+			break
+		}
+	}
+	return result
+}
+
+// optimize 按择优目标返回最优的券组合，没有任何券能产生优惠时返回 nil。
+// base 是在 fact 上已经生效的券（如先行计算的店铺券），它们不会出现在结果中，
+// 只用于检查新加入的券能否与之叠加。
+func (o *offerOptimizer) optimize(fact domain.Fact, base, candidates []*offerCandidate) *offerPlan {
+	return o.choose(o.plans(fact, base, candidates), 0)
+}
+
+// plans 返回在 base 之上可以加入的所有能产生优惠的组合，base 的含义同 optimize。
+func (o *offerOptimizer) plans(fact domain.Fact, base, candidates []*offerCandidate) []*offerPlan {
+	plans := make([]*offerPlan, 0)
+	consider := func(plan *offerPlan) {
+		if plan.value > 0 {
+			plans = append(plans, plan)
+		}
+	}
+
+	stackable := make([]*offerCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if !c.template.IsExclusive {
+			stackable = append(stackable, c)
+		} else if len(base) == 0 {
+			consider(o.buildPlan(fact, []*offerCandidate{c}))
+		}
+	}
+
+	if len(stackable) <= maxExhaustiveCandidates {
+		o.enumerate(stackable, base, func(chosen []*offerCandidate) {
+			consider(o.buildPlan(fact, chosen[len(base):]))
+		})
+	} else {
+		consider(o.greedy(fact, base, stackable))
+	}
+	return plans
+}
+
+// enumerate 深度优先地枚举所有可以同时使用的组合，visit 收到的组合以 chosen 为前缀。
+func (o *offerOptimizer) enumerate(candidates, chosen []*offerCandidate, visit func([]*offerCandidate)) {
+	for i, c := range candidates {
+		if !o.compatible(chosen, c) {
+			continue
+		}
+		next := append(append([]*offerCandidate(nil), chosen...), c)
+		visit(next)
+		o.enumerate(candidates[i+1:], next, visit)
+	}
+}
+
+// greedy 按优先级依次尝试加入每张券，只保留能提升组合价值的券。
+func (o *offerOptimizer) greedy(fact domain.Fact, base, candidates []*offerCandidate) *offerPlan {
+	ordered := append([]*offerCandidate(nil), candidates...)
+	sortByPriority(ordered)
+
+	chosen := base
+	best := &offerPlan{}
+	for _, c := range ordered {
+		if !o.compatible(chosen, c) {
+			continue
+		}
+		next := append(append([]*offerCandidate(nil), chosen...), c)
+		if plan := o.buildPlan(fact, next[len(base):]); plan.value > best.value {
+			chosen, best = next, plan
+		}
+	}
+	return best
+}
+
+// compatibleAll 判断一组券能否依次加入已选的组合。
+func (o *offerOptimizer) compatibleAll(chosen, added []*offerCandidate) bool {
+	for _, c := range added {
+		if !o.compatible(chosen, c) {
+			return false
+		}
+		chosen = append(append([]*offerCandidate(nil), chosen...), c)
+	}
+	return true
+}
+
+// compatible 判断一张券能否加入已选的组合。
+func (o *offerOptimizer) compatible(chosen []*offerCandidate, c *offerCandidate) bool {
+	other, _ := o.conflictWith(chosen, c)
+	return other == nil
+}
+
+// conflictWith 返回已选组合中与 c 冲突的券及原因，没有冲突时返回 nil：
+// 互斥券不能与任何券同时使用，同一模板的券在一笔订单中只能使用一张，且组合中的促销类型必须满足叠加规则。
+func (o *offerOptimizer) conflictWith(chosen []*offerCandidate, c *offerCandidate) (*offerCandidate, string) {
+	if len(chosen) > 0 && c.template.IsExclusive {
+		return chosen[0], fmt.Sprintf("%s is exclusive", candidateName(c))
+	}
+
+	types := make([]string, 0, len(chosen))
+	for _, other := range chosen {
+		switch {
+		case other.template.IsExclusive:
+			return other, fmt.Sprintf("%s is exclusive", candidateName(other))
+		case other.template.ID == c.template.ID:
+			return other, "only one coupon per template can be used"
+		case other.template.PromotionType != c.template.PromotionType &&
+			!o.policies.CanStack([]string{other.template.PromotionType}, c.template.PromotionType):
+			return other, fmt.Sprintf("%s and %s promotions cannot be stacked", other.template.PromotionType, c.template.PromotionType)
+		}
+		types = append(types, other.template.PromotionType)
+	}
+
+	// 不同类型两两之间都可以叠加时，只可能是同类型的张数超过了上限
+	if !o.policies.CanStack(types, c.template.PromotionType) {
+		for _, other := range chosen {
+			if other.template.PromotionType == c.template.PromotionType {
+				return other, fmt.Sprintf("at most %d %s promotions can be combined",
+					o.policies[c.template.PromotionType].MaxCount, c.template.PromotionType)
+			}
+		}
+	}
+	return nil, ""
+}
+
+// candidateName 返回用于提示信息的券名称：券码，自动促销则是模板名称。
+func candidateName(c *offerCandidate) string {
+	if c.coupon != nil {
+		return fmt.Sprintf("coupon %s", c.coupon.CouponCode)
+	}
+	return fmt.Sprintf("promotion %q", c.template.Name)
+}
+
+// buildPlan 按优先级依次计算组合中的每张券。
+// 每张券的优惠不会超过剩余应付金额，算不出优惠的券不会出现在结果中。
+func (o *offerOptimizer) buildPlan(fact domain.Fact, combination []*offerCandidate) *offerPlan {
+	ordered := append([]*offerCandidate(nil), combination...)
+	sortByPriority(ordered)
+
+	plan := &offerPlan{}
+	current := fact
+	for _, c := range ordered {
+		offer, err := c.strategy.Calculate(current, c.template)
+		if err != nil || offer == nil || offer.Value() <= 0 {
+			continue
+		}
+		if offer.Layer == "" {
+			offer.Layer = c.template.DiscountType.Layer()
+		}
+
+		payable := current.TotalAmount
+		if offer.Layer == domain.DiscountLayerShipping {
+			payable = current.Shipping.Fee
+		}
+		if offer.Amount > payable {
+			offer.Amount = payable
+			offer.Allocations = domain.Prorate(payable, offer.Allocations)
+		}
+
+		plan.applied = append(plan.applied, &appliedOffer{candidate: c, offer: offer})
+		plan.amount += offer.Amount
+		plan.value += offer.Value()
+		current = current.ApplyDiscount(offer)
+	}
+	return plan
+}
+
+// choose 按择优目标从可行的组合中选出一个，选中组合的价值不低于最大价值减去 tolerance，plans 为空时返回 nil。
+func (o *offerOptimizer) choose(plans []*offerPlan, tolerance int64) *offerPlan {
+	if len(plans) == 0 {
+		return nil
+	}
+	if o.objective == nil {
+		return (&toleranceObjective{}).choose(plans, tolerance)
+	}
+	return o.objective.choose(plans, tolerance)
+}
+
+// tolerance 返回择优目标在整单上最多允许让出的优惠金额，未设置目标时为 0。
+func (o *offerOptimizer) tolerance() int64 {
+	if o.objective == nil {
+		return 0
+	}
+	return o.objective.tolerance()
+}
+
+// sortByPriority 按优先级从高到低排序，优先级相同时按模板ID、券ID排序，保证计算顺序确定。
+func sortByPriority(candidates []*offerCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.template.Priority != b.template.Priority {
+			return a.template.Priority > b.template.Priority
+		}
+		if a.template.ID != b.template.ID {
+			return a.template.ID < b.template.ID
+		}
+		return candidateKey(a) < candidateKey(b)
+	})
+}
+
+// candidateKey 返回候选券的排序键。
+func candidateKey(c *offerCandidate) int64 {
+	if c.coupon == nil {
+		return 0
+	}
+	return c.coupon.ID
+}
+
+// candidates 返回组合中实际生效的券。
+func (p *offerPlan) candidates() []*offerCandidate {
+	if p == nil {
+		return nil
+	}
+	result := make([]*offerCandidate, 0, len(p.applied))
+	for _, a := range p.applied {
+		result = append(result, a.candidate)
+	}
+	return result
+}
+
+// key 返回组合中实际生效的券的标识，用于去掉结果相同的组合。
+func (p *offerPlan) key() string {
+	parts := make([]string, 0, len(p.applied))
+	for _, a := range p.applied {
+		parts = append(parts, fmt.Sprintf("%d/%d", a.candidate.template.ID, candidateKey(a.candidate)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// merge 将另一个组合的结果追加到当前组合之后，任一方为 nil 时返回另一方。
+func (p *offerPlan) merge(other *offerPlan) *offerPlan {
+	if p == nil {
+		return other
+	}
+	if other == nil {
+		return p
+	}
+	return &offerPlan{
+		applied: append(append([]*appliedOffer(nil), p.applied...), other.applied...),
+		amount:  p.amount + other.amount,
+		value:   p.value + other.value,
+	}
+}
+
+// remapLines 将基于子 Fact 计算的分摊明细映射回原 Fact 的行下标。
+func (p *offerPlan) remapLines(lineIndexes []int) {
+	if p == nil {
+		return
+	}
+	for _, a := range p.applied {
+		for i := range a.offer.Allocations {
+			a.offer.Allocations[i].LineIndex = lineIndexes[a.offer.Allocations[i].LineIndex]
+		}
+	}
+}
+
+// summary 将组合汇总为一个 DiscountApplication：金额与分摊明细相加，策略名和描述依次拼接。
+// 积分、返现等权益无法简单相加，只保留在每张券各自的结果中。
+func (p *offerPlan) summary(layer domain.DiscountLayer) *domain.DiscountApplication {
+	summary := &domain.DiscountApplication{Amount: p.amount, Layer: layer}
+	if len(p.applied) == 1 {
+		summary.Reward = p.applied[0].offer.Reward
+	}
+
+	names := make([]string, 0, len(p.applied))
+	descriptions := make([]string, 0, len(p.applied))
+	perLine := make(map[int]*domain.LineAllocation)
+	for _, a := range p.applied {
+		names = append(names, a.offer.StrategyName)
+		descriptions = append(descriptions, a.offer.Description)
+		for _, alloc := range a.offer.Allocations {
+			if existing, ok := perLine[alloc.LineIndex]; ok {
+				existing.Amount += alloc.Amount
+			} else {
+				merged := alloc
+				perLine[alloc.LineIndex] = &merged
+			}
+		}
+	}
+	summary.StrategyName = strings.Join(names, "+")
+	summary.Description = strings.Join(descriptions, "；")
+
+	for _, alloc := range perLine {
+		summary.Allocations = append(summary.Allocations, *alloc)
+	}
+	sort.Slice(summary.Allocations, func(i, j int) bool {
+		return summary.Allocations[i].LineIndex < summary.Allocations[j].LineIndex
+	})
+	return summary
+}
@@ -0,0 +1,373 @@
+// internal/application/offer_optimizer_test.go
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/discount"
+)
+
+func newCandidate(t *testing.T, id int64, discountType domain.DiscountType, properties string, priority int, exclusive bool) *offerCandidate {
+	t.Helper()
+	strategy, err := discount.NewStrategyFactory().CreateStrategy(discountType)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &offerCandidate{
+		coupon: &domain.UserCoupon{ID: id, TemplateID: id},
+		template: &domain.PromotionTemplate{
+			ID:                 id,
+			DiscountType:       discountType,
+			DiscountProperties: properties,
+			Priority:           priority,
+			IsExclusive:        exclusive,
+		},
+		strategy: strategy,
+	}
+}
+
+func TestOfferOptimizer_StacksByPriorityOnRemainingAmount(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	candidates := []*offerCandidate{
+		// 满100减20，优先级高，先计算
+		newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 10000, "amount": 2000}`, 100, false),
+		// 9折，基于减20后的80元计算，优惠8元
+		newCandidate(t, 2, domain.DiscountTypePercentage, `{"percentage": 90}`, 50, false),
+		// 互斥的满100减25，不如叠加划算
+		newCandidate(t, 3, domain.DiscountTypeFixedAmount, `{"threshold": 10000, "amount": 2500}`, 10, true),
+	}
+
+	plan := (&offerOptimizer{}).optimize(fact, nil, candidates)
+	if plan == nil {
+		t.Fatal("expected a plan")
+	}
+	if plan.amount != 2800 {
+		t.Errorf("expected total discount 2800; got %d", plan.amount)
+	}
+	if len(plan.applied) != 2 || plan.applied[0].candidate.template.ID != 1 || plan.applied[1].offer.Amount != 800 {
+		t.Errorf("expected coupon 1 then coupon 2 (800); got %+v", plan.applied)
+	}
+}
+
+func TestOfferOptimizer_ExclusiveCouponStandsAlone(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	candidates := []*offerCandidate{
+		newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, false),
+		newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 50, false),
+		newCandidate(t, 3, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 12000}`, 10, true),
+	}
+
+	plan := (&offerOptimizer{}).optimize(fact, nil, candidates)
+	if len(plan.applied) != 1 || plan.applied[0].candidate.template.ID != 3 {
+		t.Fatalf("expected only the exclusive coupon; got %+v", plan.applied)
+	}
+	// 优惠不能超过应付金额
+	if plan.amount != 10000 {
+		t.Errorf("expected discount clamped to 10000; got %d", plan.amount)
+	}
+}
+
+func TestOfferOptimizer_RespectsStackingPolicies(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	store1 := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, false)
+	store2 := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 800}`, 90, false)
+	platform := newCandidate(t, 3, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 500}`, 80, false)
+	store1.template.PromotionType = domain.PromotionTypeStoreCoupon
+	store2.template.PromotionType = domain.PromotionTypeStoreCoupon
+	platform.template.PromotionType = domain.PromotionTypePlatformSale
+
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies([]*domain.StackingPolicy{
+		{PromotionType: domain.PromotionTypeStoreCoupon, MaxCount: 1, StackableWith: []string{domain.PromotionTypePlatformSale}},
+		{PromotionType: domain.PromotionTypePlatformSale, MaxCount: 1, StackableWith: []string{domain.PromotionTypeStoreCoupon}},
+	})}
+
+	plan := optimizer.optimize(fact, nil, []*offerCandidate{store1, store2, platform})
+	if plan.amount != 1500 {
+		t.Errorf("expected one store coupon plus the platform sale (1500); got %d", plan.amount)
+	}
+	if len(plan.applied) != 2 || plan.applied[0].candidate != store1 || plan.applied[1].candidate != platform {
+		t.Errorf("expected store coupon 1 then platform sale; got %+v", plan.applied)
+	}
+}
+
+func TestOfferOptimizer_StoreCouponsOnlyDiscountOwnItems(t *testing.T) {
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 6000, Quantity: 1, StoreID: 1},
+			{SKU: "B", Price: 4000, Quantity: 1, StoreID: 2},
+		},
+		TotalAmount: 10000,
+	}
+	// 店铺1的9折券只能打折店铺1的60元商品
+	store := newCandidate(t, 1, domain.DiscountTypePercentage, `{"percentage": 90}`, 100, false)
+	store.template.PromotionType = domain.PromotionTypeStoreCoupon
+	store.template.StoreID = 1
+	// 平台满90减10基于店铺优惠后的94元计算
+	platform := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 9000, "amount": 1000}`, 50, false)
+	platform.template.PromotionType = domain.PromotionTypePlatformSale
+
+	plan := (&offerOptimizer{}).optimizeCart(fact, []*offerCandidate{platform, store})
+	if plan.amount != 1600 {
+		t.Fatalf("expected total discount 1600; got %d", plan.amount)
+	}
+	storeOffer := plan.applied[0].offer
+	if len(storeOffer.Allocations) != 1 || storeOffer.Allocations[0].LineIndex != 0 || storeOffer.Allocations[0].Amount != 600 {
+		t.Errorf("expected store coupon allocated to line 0 only; got %+v", storeOffer.Allocations)
+	}
+}
+
+func TestOfferOptimizer_StackingPoliciesSpanAllStores(t *testing.T) {
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 6000, Quantity: 1, StoreID: 1},
+			{SKU: "B", Price: 4000, Quantity: 1, StoreID: 2},
+		},
+		TotalAmount: 10000,
+	}
+	store1 := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, false)
+	store1.template.StoreID = 1
+	store2 := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 800}`, 90, false)
+	store2.template.StoreID = 2
+	platform := newCandidate(t, 3, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 500}`, 80, false)
+	store1.template.PromotionType = domain.PromotionTypeStoreCoupon
+	store2.template.PromotionType = domain.PromotionTypeStoreCoupon
+	platform.template.PromotionType = domain.PromotionTypePlatformSale
+
+	// 一笔订单最多使用一张店铺券，即使两张券属于不同店铺
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies([]*domain.StackingPolicy{
+		{PromotionType: domain.PromotionTypeStoreCoupon, MaxCount: 1, StackableWith: []string{domain.PromotionTypePlatformSale}},
+		{PromotionType: domain.PromotionTypePlatformSale, MaxCount: 1, StackableWith: []string{domain.PromotionTypeStoreCoupon}},
+	})}
+
+	plan := optimizer.optimizeCart(fact, []*offerCandidate{store1, store2, platform})
+	if plan == nil || len(plan.applied) != 2 {
+		t.Fatalf("expected one store coupon plus the platform sale; got %+v", plan)
+	}
+	if plan.applied[0].candidate != store1 || plan.applied[1].candidate != platform || plan.amount != 1500 {
+		t.Errorf("expected store coupon 1 then the platform sale (1500); got %+v, amount %d", plan.applied, plan.amount)
+	}
+}
+
+func TestOfferOptimizer_ChoosesStoreCouponsJointlyAcrossStores(t *testing.T) {
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 6000, Quantity: 1, StoreID: 1},
+			{SKU: "B", Price: 20000, Quantity: 1, StoreID: 2},
+		},
+		TotalAmount: 26000,
+	}
+	newStoreCoupon := func(id, storeID int64, amount string, exclusive bool) *offerCandidate {
+		c := newCandidate(t, id, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": `+amount+`}`, 100, exclusive)
+		c.template.PromotionType, c.template.StoreID = domain.PromotionTypeStoreCoupon, storeID
+		return c
+	}
+
+	// 店铺1只有一张5元的互斥券，不能挡住店铺2的100元可叠加券
+	small := newStoreCoupon(1, 1, "500", true)
+	large := newStoreCoupon(2, 2, "10000", false)
+	plan := (&offerOptimizer{}).optimizeCart(fact, []*offerCandidate{small, large})
+	if plan == nil || len(plan.applied) != 1 || plan.applied[0].candidate != large {
+		t.Fatalf("expected only store 2's coupon; got %+v", plan)
+	}
+
+	// 整单最多一张店铺券时，名额应留给店铺2更大的券，而不是被店铺ID较小的店铺先占用
+	small = newStoreCoupon(1, 1, "500", false)
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies([]*domain.StackingPolicy{
+		{PromotionType: domain.PromotionTypeStoreCoupon, MaxCount: 1},
+	})}
+	plan = optimizer.optimizeCart(fact, []*offerCandidate{small, large})
+	if plan == nil || len(plan.applied) != 1 || plan.applied[0].candidate != large || plan.amount != 10000 {
+		t.Errorf("expected store 2's coupon (10000); got %+v", plan)
+	}
+}
+
+func TestOfferOptimizer_QuoteReportsConflictingCoupons(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	first := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, false)
+	second := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 800}`, 90, false)
+	first.coupon.CouponCode, second.coupon.CouponCode = "STORE-A", "STORE-B"
+	first.template.PromotionType = domain.PromotionTypeStoreCoupon
+	second.template.PromotionType = domain.PromotionTypeStoreCoupon
+
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies([]*domain.StackingPolicy{
+		{PromotionType: domain.PromotionTypeStoreCoupon, MaxCount: 1},
+	})}
+
+	plan, problems := optimizer.quoteCart(fact, []*offerCandidate{second, first})
+	if len(problems) != 1 || problems[0].candidate != second {
+		t.Fatalf("expected the lower priority coupon to conflict; got %+v", problems)
+	}
+	if expected := "coupon STORE-B cannot be combined with coupon STORE-A: at most 1 STORE_COUPON promotions can be combined"; problems[0].message != expected {
+		t.Errorf("unexpected message %q", problems[0].message)
+	}
+	if plan == nil || plan.amount != 1000 {
+		t.Errorf("expected the accepted coupon to be quoted; got %+v", plan)
+	}
+}
+
+func TestOfferOptimizer_QuoteRejectsStoreCouponsAcrossStores(t *testing.T) {
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 6000, Quantity: 1, StoreID: 1},
+			{SKU: "B", Price: 4000, Quantity: 1, StoreID: 2},
+		},
+		TotalAmount: 10000,
+	}
+	first := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, false)
+	second := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 800}`, 90, false)
+	first.coupon.CouponCode, second.coupon.CouponCode = "STORE-1", "STORE-2"
+	first.template.PromotionType, first.template.StoreID = domain.PromotionTypeStoreCoupon, 1
+	second.template.PromotionType, second.template.StoreID = domain.PromotionTypeStoreCoupon, 2
+
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies([]*domain.StackingPolicy{
+		{PromotionType: domain.PromotionTypeStoreCoupon, MaxCount: 1},
+	})}
+
+	// 每个店铺各选一张店铺券，仍然超过了整笔订单一张的上限
+	plan, problems := optimizer.quoteCart(fact, []*offerCandidate{second, first})
+	if len(problems) != 1 || problems[0].candidate != second {
+		t.Fatalf("expected the second store's coupon to conflict; got %+v", problems)
+	}
+	if expected := "coupon STORE-2 cannot be combined with coupon STORE-1: at most 1 STORE_COUPON promotions can be combined"; problems[0].message != expected {
+		t.Errorf("unexpected message %q", problems[0].message)
+	}
+	if plan == nil || len(plan.applied) != 1 || plan.amount != 1000 {
+		t.Errorf("expected only the first store's coupon to be quoted; got %+v", plan)
+	}
+}
+
+func TestOfferOptimizer_RanksRewardsByMonetaryValue(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	// 立减10元
+	cash := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, true)
+	// 赠送价值15元的赠品，不减少应付金额，但折算价值更高
+	gift := newCandidate(t, 2, domain.DiscountTypeGift, `{"threshold": 0, "gift_sku": "MUG", "gift_value": 1500}`, 10, true)
+	// 返现20元按6折折算为12元
+	cashback := newCandidate(t, 3, domain.DiscountTypeCashback, `{"threshold": 0, "amount": 2000, "value_rate": 60}`, 50, true)
+
+	plan := (&offerOptimizer{}).optimize(fact, nil, []*offerCandidate{cash, gift, cashback})
+	if plan == nil || len(plan.applied) != 1 || plan.applied[0].candidate != gift {
+		t.Fatalf("expected the gift to beat the cash discount; got %+v", plan)
+	}
+	if plan.amount != 0 || plan.value != 1500 {
+		t.Errorf("expected amount 0 and value 1500; got amount %d, value %d", plan.amount, plan.value)
+	}
+}
+
+func TestOfferOptimizer_ObjectivesWithinTolerance(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, StoreID: 1, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	// 长期有效的店铺大额券，与两张平台券都互斥
+	longLived := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 2000}`, 100, true)
+	longLived.coupon.ExpiryDate = time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	longLived.template.StoreID = 1
+	// 明天过期、少优惠3元的平台券
+	expiring := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1700}`, 50, true)
+	expiring.coupon.ExpiryDate = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	// 下月过期、少优惠8元的平台券，超出容忍金额
+	cheap := newCandidate(t, 3, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1200}`, 10, true)
+	cheap.coupon.ExpiryDate = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	candidates := []*offerCandidate{longLived, expiring, cheap}
+
+	tests := []struct {
+		objective Objective
+		expected  *offerCandidate
+	}{
+		{ObjectiveMaxDiscount, longLived},
+		{ObjectiveExpiringSoon, expiring},
+		{ObjectivePlatformFunded, expiring},
+	}
+	for _, tt := range tests {
+		objective, err := newObjective(tt.objective, DefaultObjectiveTolerance)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		plan := (&offerOptimizer{objective: objective}).optimizeCart(fact, candidates)
+		if len(plan.applied) != 1 || plan.applied[0].candidate != tt.expected {
+			t.Errorf("%s: expected coupon %d; got %+v", tt.objective, tt.expected.coupon.ID, plan.applied)
+		}
+	}
+
+	if _, err := newObjective("CHEAPEST", DefaultObjectiveTolerance); err == nil {
+		t.Error("expected an error for an unsupported objective")
+	}
+}
+
+func TestOfferOptimizer_ToleranceIsSpentOncePerCart(t *testing.T) {
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 2000, Quantity: 1, StoreID: 1},
+			{SKU: "B", Price: 2000, Quantity: 1, StoreID: 2},
+		},
+		TotalAmount: 4000,
+	}
+	// 每个店铺都有一张长期有效的20元券和一张快过期、少优惠3元的满20可用券，
+	// 两张券叠加时长期券先把店铺商品减到0，快过期的券不再满足门槛，组合等价于只用长期券
+	var candidates []*offerCandidate
+	for i, storeID := range []int64{1, 2} {
+		longLived := newCandidate(t, int64(10*i+1), domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 2000}`, 100, false)
+		longLived.coupon.ExpiryDate = time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+		expiring := newCandidate(t, int64(10*i+2), domain.DiscountTypeFixedAmount, `{"threshold": 2000, "amount": 1700}`, 10, false)
+		expiring.coupon.ExpiryDate = time.Date(2026, 10, 18+i, 0, 0, 0, 0, time.UTC)
+		for _, c := range []*offerCandidate{longLived, expiring} {
+			c.template.PromotionType = domain.PromotionTypeStoreCoupon
+			c.template.StoreID = storeID
+		}
+		candidates = append(candidates, longLived, expiring)
+	}
+
+	objective, err := newObjective(ObjectiveExpiringSoon, DefaultObjectiveTolerance)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plan := (&offerOptimizer{objective: objective}).optimizeCart(fact, candidates)
+
+	// 两个店铺都换成快过期的券会让出6元，超过了整单5元的容忍金额，只有店铺1能换
+	if plan == nil || plan.value < 4000-DefaultObjectiveTolerance {
+		t.Fatalf("expected at most %d to be given up across the cart; got %+v", DefaultObjectiveTolerance, plan)
+	}
+	if len(plan.applied) != 2 || plan.applied[0].candidate != candidates[1] || plan.applied[1].candidate != candidates[2] {
+		t.Errorf("expected store 1's expiring coupon and store 2's long-lived coupon; got %+v", plan.applied)
+	}
+}
+
+func TestOfferOptimizer_TieBreaksByExpiryThenPriority(t *testing.T) {
+	fact := domain.Fact{
+		Items:       []domain.CartItem{{SKU: "A", Price: 10000, Quantity: 1}},
+		TotalAmount: 10000,
+	}
+	later := newCandidate(t, 1, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 100, true)
+	later.coupon.ExpiryDate = time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	sooner := newCandidate(t, 2, domain.DiscountTypeFixedAmount, `{"threshold": 0, "amount": 1000}`, 10, true)
+	sooner.coupon.ExpiryDate = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	plan := (&offerOptimizer{}).optimize(fact, nil, []*offerCandidate{later, sooner})
+	if plan.applied[0].candidate != sooner {
+		t.Errorf("expected the sooner expiring coupon on equal value; got coupon %d", plan.applied[0].candidate.coupon.ID)
+	}
+
+	sooner.coupon.ExpiryDate = later.coupon.ExpiryDate
+	plan = (&offerOptimizer{}).optimize(fact, nil, []*offerCandidate{later, sooner})
+	if plan.applied[0].candidate != later {
+		t.Errorf("expected the higher priority coupon on equal expiry; got coupon %d", plan.applied[0].candidate.coupon.ID)
+	}
+}
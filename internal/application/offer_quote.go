@@ -0,0 +1,94 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// selectionProblem 描述用户选择的一张券为什么不能按所选组合使用。
+type selectionProblem struct {
+	candidate *offerCandidate
+	message   string
+}
+
+// quoteCart 按用户选择的券计算优惠，不做任何择优。
+// 与 optimizeCart 相同，店铺券先在各自店铺的子 Fact 上计算，平台级促销再作用于整单；
+// 叠加规则对整笔订单生效，前面店铺已接受的券同样参与冲突检查。
+// 与已选券冲突的券、以及在组合中算不出优惠的券都会作为问题返回。
+func (o *offerOptimizer) quoteCart(fact domain.Fact, selected []*offerCandidate) (*offerPlan, []*selectionProblem) {
+	var problems []*selectionProblem
+
+	byStore := make(map[int64][]*offerCandidate)
+	storeIDs := make([]int64, 0)
+	platform := make([]*offerCandidate, 0, len(selected))
+	for _, c := range selected {
+		if !c.template.IsStoreScoped() {
+			platform = append(platform, c)
+			continue
+		}
+		if _, ok := byStore[c.template.StoreID]; !ok {
+			storeIDs = append(storeIDs, c.template.StoreID)
+		}
+		byStore[c.template.StoreID] = append(byStore[c.template.StoreID], c)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+
+	var plan *offerPlan
+	var base []*offerCandidate
+	current := fact
+	for _, storeID := range storeIDs {
+		accepted := o.accept(base, byStore[storeID], &problems)
+		sub, lineIndexes := current.ForStore(storeID)
+		storePlan := o.buildPlan(sub, accepted)
+		storePlan.remapLines(lineIndexes)
+		for _, a := range storePlan.applied {
+			current = current.ApplyDiscount(a.offer)
+		}
+		plan = plan.merge(storePlan)
+		base = append(base, accepted...)
+	}
+
+	accepted := o.accept(base, platform, &problems)
+	plan = plan.merge(o.buildPlan(current, accepted))
+	base = append(base, accepted...)
+
+	// buildPlan 会跳过算不出优惠的券，例如前面的券扣减后不再满足门槛
+	applied := make(map[*offerCandidate]bool)
+	for _, a := range plan.applied {
+		applied[a.candidate] = true
+	}
+	for _, c := range base {
+		if !applied[c] {
+			problems = append(problems, &selectionProblem{
+				candidate: c,
+				message:   fmt.Sprintf("%s gives no discount when combined with the other selected coupons", candidateName(c)),
+			})
+		}
+	}
+
+	if len(plan.applied) == 0 {
+		plan = nil
+	}
+	return plan, problems
+}
+
+// accept 按优先级从高到低依次把券加入以 base 为前缀的组合，返回新加入的券，冲突的券记录为问题。
+func (o *offerOptimizer) accept(base, candidates []*offerCandidate, problems *[]*selectionProblem) []*offerCandidate {
+	ordered := append([]*offerCandidate(nil), candidates...)
+	sortByPriority(ordered)
+
+	chosen := append([]*offerCandidate(nil), base...)
+	for _, c := range ordered {
+		if other, reason := o.conflictWith(chosen, c); other != nil {
+			*problems = append(*problems, &selectionProblem{
+				candidate: c,
+				message:   fmt.Sprintf("%s cannot be combined with %s: %s", candidateName(c), candidateName(other), reason),
+			})
+			continue
+		}
+		chosen = append(chosen, c)
+	}
+	return chosen[len(base):]
+}
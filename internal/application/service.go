@@ -9,11 +9,11 @@ import (
 
 type PromotionService interface {
 	// CreatePromotionTemplate 创建一个新的促销活动模板
-	// 这是所有规则的起点
+	// 这是所有规则的起点，规则和优惠参数不合法时返回 *ValidationError
 	CreatePromotionTemplate(ctx context.Context, req *CreateTemplateRequest) (*TemplateResponse, error)
 
 	// UpdatePromotionTemplate 更新一个促销活动模板
-	// 内部实现应是创建新版本，而不是修改旧版本
+	// 内部实现应是创建新版本，而不是修改旧版本，校验规则与创建时相同
 	UpdatePromotionTemplate(ctx context.Context, req *UpdateTemplateRequest) (*TemplateResponse, error)
 
 	// DeactivatePromotionTemplate 停用一个促销活动
@@ -25,6 +25,28 @@ type PromotionService interface {
 	// GetActiveTemplateByGroup 获取一个活动当前生效的版本
 	GetActiveTemplateByGroup(ctx context.Context, templateGroupID string) (*TemplateResponse, error)
 
+	// ListDiscountTypes 列出所有支持的优惠类型及其参数结构
+	// 管理后台据此渲染模板表单，而不必猜测 DiscountProperties 的JSON格式
+	ListDiscountTypes(ctx context.Context) ([]*DiscountTypeResponse, error)
+
+	// ConvertRule 将可视化规则编辑器生成的 JSON 规则转换为等价的 CEL 表达式
+	// 规则无法转换时返回 *ValidationError，字段名指明出错条件的位置，如 "rule_definition.all[1].any[0]"
+	ConvertRule(ctx context.Context, req *ConvertRuleRequest) (*ConvertRuleResponse, error)
+
+	// MigrateRulesToCEL 将所有激活模板中的 JSON 规则转换为 CEL，apply 为 false 时只预览不写入
+	// 写入时为每个模板创建使用 CEL 的新版本；转换失败的模板保持原样，并在结果中说明原因
+	MigrateRulesToCEL(ctx context.Context, apply bool) (*RuleMigrationResponse, error)
+
+	// ListStackingPolicies 列出所有促销类型的叠加规则
+	ListStackingPolicies(ctx context.Context) ([]*StackingPolicyResponse, error)
+
+	// SaveStackingPolicy 创建或覆盖一种促销类型的叠加规则，择优计算时据此限制券的组合
+	// 参数不合法时返回 *ValidationError
+	SaveStackingPolicy(ctx context.Context, req *StackingPolicyRequest) (*StackingPolicyResponse, error)
+
+	// DeleteStackingPolicy 删除一种促销类型的叠加规则，删除后该类型只受模板自身的 IsExclusive 约束
+	DeleteStackingPolicy(ctx context.Context, promotionType string) error
+
 	// IssueCouponToUser 为指定用户发放一张优惠券
 	// 这是最核心的发券接口
 	IssueCouponToUser(ctx context.Context, req *IssueCouponRequest) (*UserCouponResponse, error)
@@ -35,12 +57,27 @@ type PromotionService interface {
 	// CalculateBestOffer 评估并计算最优优惠
 	// 这是规则引擎的核心价值所在，也是性能要求最高的接口
 	// 它接收一个“事实”对象，包含了计算所需的所有上下文 [cite: 39]
-	CalculateBestOffer(ctx context.Context, fact *domain.Fact) (*DiscountApplicationResponse, error)
+	// 商品优惠与运费优惠分层择优，互不竞争；同一层级内的券按叠加规则组合
+	// opts.Explain 为 true 时同时说明用户持有的每张券被使用或未被使用的原因
+	// opts.Objective 指定择优目标，不支持的目标返回 *ValidationError
+	CalculateBestOffer(ctx context.Context, fact *domain.Fact, opts OfferOptions) (*BestOfferResponse, error)
+
+	// QuoteOffer 按用户在结算页手动选择的券计算优惠和分摊明细，不做择优
+	// 所选的券不属于该用户、不可用或互相冲突时返回 *ValidationError，并指明冲突的券
+	QuoteOffer(ctx context.Context, req *QuoteOfferRequest) (*BestOfferResponse, error)
 
 	// GetApplicableCoupons 获取用户在当前“事实”下所有可用的优惠券列表
 	// 用于在购物车或结算页向用户展示可用优惠券
 	GetApplicableCoupons(ctx context.Context, fact *domain.Fact, userID int64) ([]*UserCouponResponse, error)
 
+	// ExplainApplicableCoupons 与 GetApplicableCoupons 相同，但同时说明用户持有的每张券可用或不可用的原因
+	// 包括已过期、已冻结、模板缺失、规则不满足、规则执行出错等
+	ExplainApplicableCoupons(ctx context.Context, fact *domain.Fact, userID int64) (*ApplicableCouponsResponse, error)
+
+	// GetCheckoutCoupons 返回结算页的可用与不可用优惠券列表
+	// 不可用的券带有原因码和面向用户的文案（如“差¥23可用”、“仅限VIP”），前端无需自行推断
+	GetCheckoutCoupons(ctx context.Context, fact *domain.Fact, userID int64) (*CheckoutCouponsResponse, error)
+
 	// FreezeUserCoupon 冻结用户优惠券（SAGA事务-预备）
 	// 在订单创建但未支付时调用
 	FreezeUserCoupon(ctx context.Context, userID int64, couponCode string) error
@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/wangyingjie930/nexus-pkg/logger"
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
@@ -22,30 +23,80 @@ type promotionServiceImpl struct {
 	uow          domain.UnitOfWork
 	templateRepo domain.PromotionTemplateRepository
 	couponRepo   domain.CouponRepository
+	stackingRepo domain.StackingPolicyRepository
 	ruleEngine   domain.RuleEngine
 	strategyFty  *discount.StrategyFactory
 	tracer       trace.Tracer
+
+	defaultObjective   Objective            // 请求和渠道都未指定时使用的择优目标
+	channelObjectives  map[string]Objective // 按 Fact.Environment.Channel 配置的择优目标
+	objectiveTolerance int64                // 偏好类择优目标允许让出的优惠金额（单位：分）
+}
+
+// Option 用于定制 PromotionService 的可选配置。
+type Option func(*promotionServiceImpl)
+
+// WithDefaultObjective 设置请求和渠道都未指定时使用的择优目标。
+func WithDefaultObjective(objective Objective) Option {
+	return func(s *promotionServiceImpl) { s.defaultObjective = objective }
+}
+
+// WithChannelObjective 为某个渠道设置默认的择优目标，请求中显式指定的目标优先。
+func WithChannelObjective(channel string, objective Objective) Option {
+	return func(s *promotionServiceImpl) { s.channelObjectives[channel] = objective }
+}
+
+// WithObjectiveTolerance 设置偏好类择优目标（如优先使用快过期的券）最多允许让出的优惠金额（单位：分），
+// 按每个优惠层级的整单计算，不随店铺数量累加。
+func WithObjectiveTolerance(tolerance int64) Option {
+	return func(s *promotionServiceImpl) { s.objectiveTolerance = tolerance }
+}
+
+// WithTimezone 设置公式类优惠中 hourOfDay、dayOfWeek 等函数使用的时区，默认为服务器本地时区。
+// 应与传入的规则引擎使用同一个时区（rule.WithTimezone），否则同一个时段条件在规则和公式中的结果会不一致。
+func WithTimezone(loc *time.Location) Option {
+	return func(s *promotionServiceImpl) { s.strategyFty = discount.NewStrategyFactory(discount.WithTimezone(loc)) }
 }
 
 // NewPromotionService 创建一个新的 PromotionService 实例
+// ruleEngine 需要能处理模板中出现的所有规则语言，通常是 rule.NewRoutingRuleEngine 组合出的引擎
+// 配置了不支持的择优目标时返回错误，由调用方在启动时处理，而不是让每个请求都失败
 func NewPromotionService(
 	uow domain.UnitOfWork,
 	templateRepo domain.PromotionTemplateRepository,
 	couponRepo domain.CouponRepository,
+	stackingRepo domain.StackingPolicyRepository,
+	ruleEngine domain.RuleEngine,
 	tracer trace.Tracer,
-) PromotionService {
-	engine, err := rule.NewCelRuleEngine()
-	if err != nil {
-		logger.Ctx(context.Background()).Fatal().Err(err).Send()
-	}
-	return &promotionServiceImpl{
+	opts ...Option,
+) (PromotionService, error) {
+	s := &promotionServiceImpl{
 		uow:          uow,
 		templateRepo: templateRepo,
 		couponRepo:   couponRepo,
-		ruleEngine:   engine,                        // 直接实例化基础设施层的具体实现
+		stackingRepo: stackingRepo,
+		ruleEngine:   ruleEngine,
 		strategyFty:  discount.NewStrategyFactory(), // 工厂模式 [cite: 156]
 		tracer:       tracer,
+
+		defaultObjective:   ObjectiveMaxDiscount,
+		channelObjectives:  make(map[string]Objective),
+		objectiveTolerance: DefaultObjectiveTolerance,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	configured := []Objective{s.defaultObjective}
+	for _, objective := range s.channelObjectives {
+		configured = append(configured, objective)
+	}
+	for _, objective := range configured {
+		if _, err := newObjective(objective, s.objectiveTolerance); err != nil {
+			return nil, err
+		}
 	}
+	return s, nil
 }
 
 // CreatePromotionTemplate 实现了不可变性设计 [cite: 215]
@@ -59,16 +110,25 @@ func (s *promotionServiceImpl) CreatePromotionTemplate(ctx context.Context, req
 		Name:               req.Name,
 		Description:        req.Description,
 		PromotionType:      req.PromotionType,
+		StoreID:            req.StoreID,
+		RuleLanguage:       domain.ParseRuleLanguage(req.RuleLanguage),
 		RuleDefinition:     req.RuleDefinition,
+		RuleHint:           req.RuleHint,
 		DiscountType:       domain.DiscountType(req.DiscountType),
 		DiscountProperties: req.DiscountProperties,
 		StartDate:          req.StartDate,
 		EndDate:            req.EndDate,
 		IsExclusive:        req.IsExclusive,
+		IsAutomatic:        req.IsAutomatic,
 		Priority:           req.Priority,
 		IsActive:           true, // 默认激活
 	}
 
+	if err := s.validateTemplate(template); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	if err := s.templateRepo.Create(ctx, template); err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -91,22 +151,37 @@ func (s *promotionServiceImpl) UpdatePromotionTemplate(ctx context.Context, req
 		return nil, fmt.Errorf("promotion template group with ID %s not found", req.TemplateGroupID)
 	}
 
+	// 未指定规则语言时沿用上一版本，否则 JSON 规则会被当作 CEL 编译
+	ruleLanguage := latest.RuleLanguage
+	if req.RuleLanguage != "" {
+		ruleLanguage = domain.ParseRuleLanguage(req.RuleLanguage)
+	}
+
 	newVersion := &domain.PromotionTemplate{
 		TemplateGroupID:    latest.TemplateGroupID,
 		Version:            latest.Version + 1, // 版本号递增
 		Name:               req.Name,
 		Description:        req.Description,
 		PromotionType:      latest.PromotionType, // 类型等核心属性不可变
+		StoreID:            latest.StoreID,
+		RuleLanguage:       ruleLanguage,
 		RuleDefinition:     req.RuleDefinition,
+		RuleHint:           req.RuleHint,
 		DiscountType:       domain.DiscountType(req.DiscountType),
 		DiscountProperties: req.DiscountProperties,
 		StartDate:          req.StartDate,
 		EndDate:            req.EndDate,
 		IsExclusive:        req.IsExclusive,
+		IsAutomatic:        req.IsAutomatic,
 		Priority:           req.Priority,
 		IsActive:           true, // 新版本默认为激活状态
 	}
 
+	if err := s.validateTemplate(newVersion); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	err = s.uow.Execute(ctx, func(repoProvider domain.RepositoryProvider) error {
 		// 2. 停用旧版本 (如果当前是激活的)
 		if latest.IsActive {
@@ -160,6 +235,155 @@ func (s *promotionServiceImpl) GetActiveTemplateByGroup(ctx context.Context, tem
 	return toTemplateResponse(template), nil
 }
 
+func (s *promotionServiceImpl) ListDiscountTypes(ctx context.Context) ([]*DiscountTypeResponse, error) {
+	descriptors := s.strategyFty.SupportedTypes()
+	resp := make([]*DiscountTypeResponse, 0, len(descriptors))
+	for _, d := range descriptors {
+		resp = append(resp, toDiscountTypeResponse(d))
+	}
+	return resp, nil
+}
+
+func (s *promotionServiceImpl) ConvertRule(ctx context.Context, req *ConvertRuleRequest) (*ConvertRuleResponse, error) {
+	expression, err := s.convertRule(req.RuleDefinition)
+	if err != nil {
+		verr := &ValidationError{Message: "invalid json rule"}
+		field := "rule_definition"
+		var convErr *rule.ConversionError
+		if errors.As(err, &convErr) && convErr.Path != "" {
+			field += "." + convErr.Path
+			err = errors.New(convErr.Message)
+		}
+		verr.add(field, "%v", err)
+		return nil, verr
+	}
+	return &ConvertRuleResponse{RuleLanguage: string(domain.RuleLanguageCEL), RuleDefinition: expression}, nil
+}
+
+// MigrateRulesToCEL 为规则可以转换的激活模板各创建一个使用 CEL 的新版本，并像 UpdatePromotionTemplate 一样停用旧版本，
+// 所有写入在同一个事务中完成。已发放的券仍引用原版本的模板ID，结果中记录了新旧版本ID的对应关系。
+func (s *promotionServiceImpl) MigrateRulesToCEL(ctx context.Context, apply bool) (*RuleMigrationResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "application.MigrateRulesToCEL")
+	defer span.End()
+
+	templates, err := s.templateRepo.FindActiveByRuleLanguage(ctx, domain.RuleLanguageJSON)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	resp := &RuleMigrationResponse{Applied: apply, Templates: make([]*RuleMigrationItemResponse, 0, len(templates))}
+	var pending []*domain.PromotionTemplate // 转换成功、待写入新版本的模板，与 items 一一对应
+	var items []*RuleMigrationItemResponse
+	for _, t := range templates {
+		item := &RuleMigrationItemResponse{TemplateID: t.ID, TemplateName: t.Name, JSONRule: t.RuleDefinition}
+		resp.Templates = append(resp.Templates, item)
+
+		expression, err := s.convertRule(t.RuleDefinition)
+		if err != nil {
+			item.Error = err.Error()
+			resp.Failed++
+			continue
+		}
+		item.CELExpression = expression
+		resp.Converted++
+		pending = append(pending, t)
+		items = append(items, item)
+	}
+	if !apply || len(pending) == 0 {
+		return resp, nil
+	}
+
+	err = s.uow.Execute(ctx, func(repoProvider domain.RepositoryProvider) error {
+		for i, t := range pending {
+			newVersion, err := s.migrateTemplate(ctx, repoProvider, t, items[i].CELExpression)
+			if err != nil {
+				return fmt.Errorf("failed to migrate template %d: %w", t.ID, err)
+			}
+			items[i].NewTemplateID = newVersion.ID
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// migrateTemplate 停用 active 并创建使用 expression 的新版本，其余属性保持不变。
+// 转换不改变优惠内容，已发放但未使用的券随之关联到新版本，否则它们仍会按旧版本的 JSON 规则计算。
+func (s *promotionServiceImpl) migrateTemplate(ctx context.Context, repos domain.RepositoryProvider, active *domain.PromotionTemplate, expression string) (*domain.PromotionTemplate, error) {
+	repo := repos.Templates()
+	latest, err := repo.FindLatestByGroupID(ctx, active.TemplateGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	newVersion := *active
+	newVersion.ID = 0
+	newVersion.Version = latest.Version + 1
+	newVersion.RuleLanguage = domain.RuleLanguageCEL
+	newVersion.RuleDefinition = expression
+	newVersion.CreatedAt, newVersion.UpdatedAt = time.Time{}, time.Time{}
+
+	active.IsActive = false
+	if err := repo.Update(ctx, active); err != nil {
+		return nil, err
+	}
+	if err := repo.Create(ctx, &newVersion); err != nil {
+		return nil, err
+	}
+	if err := repos.Coupons().MoveUnusedToTemplate(ctx, active.ID, newVersion.ID); err != nil {
+		return nil, err
+	}
+	return &newVersion, nil
+}
+
+// convertRule 将 JSON 规则转换为 CEL，并用配置的规则引擎编译转换结果，确保它能被执行。
+func (s *promotionServiceImpl) convertRule(definition string) (string, error) {
+	expression, err := rule.ConvertJSONToCEL(definition)
+	if err != nil {
+		return "", err
+	}
+	if err := s.ruleEngine.Compile(domain.PromotionRule{Language: domain.RuleLanguageCEL, Definition: expression}); err != nil {
+		return "", fmt.Errorf("converted expression %q does not compile: %w", expression, err)
+	}
+	return expression, nil
+}
+
+func (s *promotionServiceImpl) ListStackingPolicies(ctx context.Context) ([]*StackingPolicyResponse, error) {
+	policies, err := s.stackingRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*StackingPolicyResponse, 0, len(policies))
+	for _, p := range policies {
+		resp = append(resp, toStackingPolicyResponse(p))
+	}
+	return resp, nil
+}
+
+func (s *promotionServiceImpl) SaveStackingPolicy(ctx context.Context, req *StackingPolicyRequest) (*StackingPolicyResponse, error) {
+	policy := &domain.StackingPolicy{
+		PromotionType: req.PromotionType,
+		MaxCount:      req.MaxCount,
+		StackableWith: req.StackableWith,
+	}
+	if err := validateStackingPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	if err := s.stackingRepo.Save(ctx, policy); err != nil {
+		return nil, err
+	}
+	return toStackingPolicyResponse(policy), nil
+}
+
+func (s *promotionServiceImpl) DeleteStackingPolicy(ctx context.Context, promotionType string) error {
+	return s.stackingRepo.Delete(ctx, promotionType)
+}
+
 func (s *promotionServiceImpl) IssueCouponToUser(ctx context.Context, req *IssueCouponRequest) (*UserCouponResponse, error) {
 	// 1. 确认模板存在且有效
 	template, err := s.templateRepo.FindByID(ctx, req.TemplateID)
@@ -204,101 +428,194 @@ func (s *promotionServiceImpl) IssueCouponsInBatch(ctx context.Context, req *Bat
 }
 
 // CalculateBestOffer 实现了择优逻辑 [cite: 92]
-func (s *promotionServiceImpl) CalculateBestOffer(ctx context.Context, fact *domain.Fact) (*DiscountApplicationResponse, error) {
-	coupons, err := s.GetApplicableCoupons(ctx, fact, fact.User.ID)
+// 用户持有的券与无需领券的自动促销一起参与择优。
+// 在每个优惠层级内求解最优的券组合：互斥券单独使用，非互斥券按促销类型的叠加规则组合，
+// 叠加时按 Priority 从高到低依次计算，每张券都基于剩余的应付金额。
+// 店铺券只作用于所属店铺的商品，平台级促销在店铺优惠之后作用于整单。
+func (s *promotionServiceImpl) CalculateBestOffer(ctx context.Context, fact *domain.Fact, opts OfferOptions) (*BestOfferResponse, error) {
+	objective, err := s.resolveObjective(fact, opts.Objective)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(coupons) == 0 {
-		// 返回DTO响应
-		return toDiscountApplicationResponse(&domain.DiscountApplication{Amount: 0, Description: "无可用优惠"}), nil
+	evaluations, err := s.evaluateCoupons(ctx, fact, fact.User.ID, opts.Explain)
+	if err != nil {
+		return nil, err
+	}
+	automatic, err := s.evaluateAutomaticPromotions(ctx, fact)
+	if err != nil {
+		return nil, err
 	}
+	candidates := append(candidatesOf(evaluations), candidatesOf(automatic)...)
 
-	var bestOffer *domain.DiscountApplication
-	var bestOfferAmount int64 = 0
+	policies, err := s.stackingRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按优惠层级分别择优：运费券只与运费券竞争，商品券只与商品券竞争
+	byLayer := make(map[domain.DiscountLayer][]*offerCandidate)
+	for _, c := range candidates {
+		layer := c.template.DiscountType.Layer()
+		byLayer[layer] = append(byLayer[layer], c)
+	}
 
-	for _, couponResp := range coupons {
-		template, err := s.templateRepo.FindByID(ctx, couponResp.TemplateID)
-		if err != nil || template == nil {
-			continue // 跳过无效模板
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies(policies), objective: objective}
+	goods := optimizer.optimizeCart(*fact, byLayer[domain.DiscountLayerGoods])
+	shipping := optimizer.optimizeCart(*fact, byLayer[domain.DiscountLayerShipping])
+
+	// 返回DTO响应
+	resp := toBestOfferResponse(goods, shipping)
+	resp.UpsellHints = upsellHints(append(evaluations, automatic...))
+	if opts.Explain {
+		resp.Explanations = explainBestOffer(evaluations, goods, shipping)
+	}
+	return resp, nil
+}
+
+// resolveObjective 确定本次计算的择优目标：请求中指定的目标优先，其次是渠道配置的目标，最后是默认目标。
+// 请求指定了不支持的目标时返回 *ValidationError。
+func (s *promotionServiceImpl) resolveObjective(fact *domain.Fact, requested Objective) (offerObjective, error) {
+	name := requested
+	if name == "" {
+		name = s.defaultObjective
+		if objective, ok := s.channelObjectives[fact.Environment.Channel]; ok {
+			name = objective
 		}
+	}
+	objective, err := newObjective(name, s.objectiveTolerance)
+	if err != nil {
+		verr := &ValidationError{Message: "invalid offer options"}
+		verr.add("objective", "%v", err)
+		return nil, verr
+	}
+	return objective, nil
+}
 
-		// 使用策略模式计算优惠
-		strategy, err := s.strategyFty.CreateStrategy(template.DiscountType)
-		if err != nil {
+// QuoteOffer 按用户手动选择的券计算优惠，不做择优，自动促销也不参与计算。
+// 券不属于该用户、当前不可用或所选的券之间不能叠加时返回 *ValidationError，并指明有问题的券。
+func (s *promotionServiceImpl) QuoteOffer(ctx context.Context, req *QuoteOfferRequest) (*BestOfferResponse, error) {
+	fact := &req.Fact
+	verr := &ValidationError{Message: "invalid coupon selection"}
+
+	fieldOf := make(map[*offerCandidate]string)
+	byLayer := make(map[domain.DiscountLayer][]*offerCandidate)
+	seen := make(map[string]bool)
+	for i, code := range req.CouponCodes {
+		field := fmt.Sprintf("coupon_codes[%d]", i)
+		if seen[code] {
+			verr.add(field, "coupon %s is selected more than once", code)
 			continue
 		}
+		seen[code] = true
 
-		offer, err := strategy.Calculate(*fact, template)
+		coupon, err := s.couponRepo.FindByCode(ctx, code)
 		if err != nil {
+			return nil, err
+		}
+		if coupon == nil || coupon.UserID != fact.User.ID {
+			verr.add(field, "coupon %s not found or does not belong to user %d", code, fact.User.ID)
 			continue
 		}
 
-		// 择优：选择优惠金额最大的
-		if offer.Amount > bestOfferAmount {
-			bestOffer = offer
-			bestOfferAmount = offer.Amount
+		evaluation := s.evaluateCoupon(ctx, fact, coupon)
+		if evaluation.candidate == nil {
+			verr.add(field, "coupon %s is not applicable: %s", code, evaluation.reason)
+			continue
 		}
+
+		c := evaluation.candidate
+		fieldOf[c] = field
+		layer := c.template.DiscountType.Layer()
+		byLayer[layer] = append(byLayer[layer], c)
+	}
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
 	}
 
-	if bestOffer == nil {
-		// 返回DTO响应
-		return toDiscountApplicationResponse(&domain.DiscountApplication{Amount: 0, Description: "无可用优惠"}), nil
+	policies, err := s.stackingRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// 返回DTO响应
-	return toDiscountApplicationResponse(bestOffer), nil
+
+	optimizer := &offerOptimizer{policies: domain.NewStackingPolicies(policies)}
+	goods, goodsProblems := optimizer.quoteCart(*fact, byLayer[domain.DiscountLayerGoods])
+	shipping, shippingProblems := optimizer.quoteCart(*fact, byLayer[domain.DiscountLayerShipping])
+	for _, p := range append(goodsProblems, shippingProblems...) {
+		verr.add(fieldOf[p.candidate], "%s", p.message)
+	}
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
+	}
+
+	return toBestOfferResponse(goods, shipping), nil
 }
 
 // GetApplicableCoupons 筛选出在当前Fact下所有可用的优惠券
 func (s *promotionServiceImpl) GetApplicableCoupons(ctx context.Context, fact *domain.Fact, userID int64) ([]*UserCouponResponse, error) {
-	// 1. 获取用户所有未使用的优惠券
+	evaluations, err := s.evaluateCoupons(ctx, fact, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return toApplicableCouponResponses(evaluations), nil
+}
+
+// ExplainApplicableCoupons 返回可用的优惠券，以及用户持有的每张券可用或不可用的原因
+func (s *promotionServiceImpl) ExplainApplicableCoupons(ctx context.Context, fact *domain.Fact, userID int64) (*ApplicableCouponsResponse, error) {
+	evaluations, err := s.evaluateCoupons(ctx, fact, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	return &ApplicableCouponsResponse{
+		Coupons:      toApplicableCouponResponses(evaluations),
+		Explanations: explainApplicable(evaluations),
+	}, nil
+}
+
+// GetCheckoutCoupons 返回结算页的可用与不可用优惠券列表
+func (s *promotionServiceImpl) GetCheckoutCoupons(ctx context.Context, fact *domain.Fact, userID int64) (*CheckoutCouponsResponse, error) {
+	evaluations, err := s.evaluateCoupons(ctx, fact, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	return toCheckoutCouponsResponse(evaluations), nil
+}
+
+// evaluateCoupons 评估用户持有的券在当前Fact下能否使用，并为可用的券准备计算优惠所需的模板和策略。
+// includeUnavailable 为 false 时只评估未使用且未过期的券；为 true 时也会记录其它券不可用的原因。
+func (s *promotionServiceImpl) evaluateCoupons(ctx context.Context, fact *domain.Fact, userID int64, includeUnavailable bool) ([]*couponEvaluation, error) {
+	// 1. 获取用户所有的优惠券
 	userCoupons, err := s.couponRepo.FindByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	availableCoupons := make([]*domain.UserCoupon, 0)
+	coupons := make([]*domain.UserCoupon, 0)
 	for _, c := range userCoupons {
-		if c.IsAvailable() {
-			availableCoupons = append(availableCoupons, c)
+		if includeUnavailable || c.IsAvailable() {
+			coupons = append(coupons, c)
 		}
 	}
 
-	if len(availableCoupons) == 0 {
+	if len(coupons) == 0 {
 		return nil, nil
 	}
 
 	// 2. 并发检查每张券的规则是否满足 [cite: 159]
-	applicableCoupons := struct {
+	evaluations := struct {
 		sync.Mutex
-		data []*UserCouponResponse
+		data []*couponEvaluation
 	}{}
 
 	g, gCtx := errgroup.WithContext(ctx)
-	for _, coupon := range availableCoupons {
+	for _, coupon := range coupons {
 		c := coupon // copy
 		g.Go(func() error {
-			template, err := s.templateRepo.FindByID(gCtx, c.TemplateID)
-			if err != nil || template == nil {
-				return nil // 跳过无效模板
-			}
-
-			// 使用规则引擎评估LHS
-			satisfied, err := s.ruleEngine.Evaluate(template.RuleDefinition, *fact)
-			if err != nil || !satisfied {
-				logger.Ctx(gCtx).Warn().
-					Err(err).
-					Str("rule", template.RuleDefinition).
-					Int64("couponID", c.ID).
-					Msg("Rule evaluation failed")
-				return nil // 规则不满足
-			}
+			evaluation := s.evaluateCoupon(gCtx, fact, c)
 
-			// 如果满足，则加入到最终列表
-			applicableCoupons.Lock()
-			applicableCoupons.data = append(applicableCoupons.data, toUserCouponResponse(c))
-			applicableCoupons.Unlock()
+			evaluations.Lock()
+			evaluations.data = append(evaluations.data, evaluation)
+			evaluations.Unlock()
 
 			return nil
 		})
@@ -309,12 +626,104 @@ func (s *promotionServiceImpl) GetApplicableCoupons(ctx context.Context, fact *d
 	}
 
 	// 按优先级和优惠金额排序 (可选，但体验更好)
-	sort.Slice(applicableCoupons.data, func(i, j int) bool {
+	sort.Slice(evaluations.data, func(i, j int) bool {
 		// ... 这里可以加入更复杂的排序逻辑
-		return applicableCoupons.data[i].ID > applicableCoupons.data[j].ID
+		return evaluations.data[i].coupon.ID > evaluations.data[j].coupon.ID
 	})
 
-	return applicableCoupons.data, nil
+	return evaluations.data, nil
+}
+
+// evaluateCoupon 评估单张券：先检查券自身的状态，再基于模板评估规则。
+func (s *promotionServiceImpl) evaluateCoupon(ctx context.Context, fact *domain.Fact, coupon *domain.UserCoupon) *couponEvaluation {
+	template, err := s.templateRepo.FindByID(ctx, coupon.TemplateID)
+
+	if outcome, reason := couponStatusOutcome(coupon); outcome != "" {
+		return &couponEvaluation{coupon: coupon, template: template, outcome: outcome, reason: reason}
+	}
+
+	if err != nil || template == nil {
+		// 跳过无效模板
+		evaluation := &couponEvaluation{coupon: coupon, outcome: CouponOutcomeTemplateMissing}
+		evaluation.reason = fmt.Sprintf("template %d not found", coupon.TemplateID)
+		if err != nil {
+			evaluation.reason = fmt.Sprintf("failed to load template %d: %v", coupon.TemplateID, err)
+		}
+		return evaluation
+	}
+
+	return s.prepareCandidate(ctx, fact, coupon, template)
+}
+
+// evaluateAutomaticPromotions 评估所有自动促销在当前Fact下能否使用。
+// 自动促销（如全场活动）无需用户持有优惠券，对每个购物车都参与择优。
+func (s *promotionServiceImpl) evaluateAutomaticPromotions(ctx context.Context, fact *domain.Fact) ([]*couponEvaluation, error) {
+	templates, err := s.templateRepo.FindActiveAutomatic(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluations := make([]*couponEvaluation, 0, len(templates))
+	for _, template := range templates {
+		evaluations = append(evaluations, s.prepareCandidate(ctx, fact, nil, template))
+	}
+
+	// 按模板ID排序，保证择优结果稳定
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].template.ID < evaluations[j].template.ID
+	})
+
+	return evaluations, nil
+}
+
+// prepareCandidate 评估模板的规则并准备计算优惠所需的策略，规则不满足或策略不可用时结果中的 candidate 为空。
+// coupon 为空表示这是一个无需领券的自动促销。店铺券的规则只基于该店铺的商品评估。
+func (s *promotionServiceImpl) prepareCandidate(ctx context.Context, fact *domain.Fact, coupon *domain.UserCoupon, template *domain.PromotionTemplate) *couponEvaluation {
+	evaluation := &couponEvaluation{coupon: coupon, template: template, fact: *fact}
+	if template.IsStoreScoped() {
+		var lineIndexes []int
+		if evaluation.fact, lineIndexes = fact.ForStore(template.StoreID); len(lineIndexes) == 0 {
+			// 购物车中没有该店铺的商品
+			evaluation.outcome = CouponOutcomeStoreNotInCart
+			evaluation.reason = fmt.Sprintf("cart has no items from store %d", template.StoreID)
+			return evaluation
+		}
+	}
+
+	// 使用规则引擎评估LHS
+	satisfied, err := s.ruleEngine.Evaluate(template.Rule(), evaluation.fact)
+	if err != nil || !satisfied {
+		event := logger.Ctx(ctx).Warn().
+			Err(err).
+			Str("ruleLanguage", string(template.Rule().EffectiveLanguage())).
+			Str("rule", template.RuleDefinition).
+			Int64("templateID", template.ID)
+		if coupon != nil {
+			event = event.Int64("couponID", coupon.ID)
+		}
+		event.Msg("Rule evaluation failed")
+
+		// 规则不满足
+		if err != nil {
+			evaluation.outcome = CouponOutcomeRuleError
+			evaluation.reason = fmt.Sprintf("rule evaluation failed: %v", err)
+		} else {
+			evaluation.outcome = CouponOutcomeRuleFalse
+			evaluation.reason = "rule is not satisfied by the current cart"
+		}
+		return evaluation
+	}
+
+	// 使用策略模式计算优惠
+	strategy, err := s.strategyFty.CreateStrategy(template.DiscountType)
+	if err != nil {
+		evaluation.outcome = CouponOutcomeUnsupported
+		evaluation.reason = err.Error()
+		return evaluation
+	}
+
+	evaluation.candidate = &offerCandidate{coupon: coupon, template: template, strategy: strategy}
+	return evaluation
 }
 
 // --- SAGA 事务方法 ---
@@ -0,0 +1,407 @@
+// internal/application/service_impl_test.go
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// memTemplateRepository 是基于内存的 domain.PromotionTemplateRepository，只用于测试。
+type memTemplateRepository struct {
+	templates []*domain.PromotionTemplate
+	createErr error // 不为 nil 时 Create 返回该错误，用于模拟写入失败
+}
+
+func (r *memTemplateRepository) FindByID(_ context.Context, id int64) (*domain.PromotionTemplate, error) {
+	for _, t := range r.templates {
+		if t.ID == id {
+			return copyTemplate(t), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memTemplateRepository) FindByGroupIDAndVersion(_ context.Context, groupID string, version int32) (*domain.PromotionTemplate, error) {
+	for _, t := range r.templates {
+		if t.TemplateGroupID == groupID && t.Version == version {
+			return copyTemplate(t), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memTemplateRepository) FindLatestByGroupID(_ context.Context, groupID string) (*domain.PromotionTemplate, error) {
+	var latest *domain.PromotionTemplate
+	for _, t := range r.templates {
+		if t.TemplateGroupID == groupID && (latest == nil || t.Version > latest.Version) {
+			latest = t
+		}
+	}
+	return copyTemplate(latest), nil
+}
+
+func (r *memTemplateRepository) FindActiveByGroupID(_ context.Context, groupID string) (*domain.PromotionTemplate, error) {
+	for _, t := range r.templates {
+		if t.TemplateGroupID == groupID && t.IsActive {
+			return copyTemplate(t), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memTemplateRepository) FindAllActiveTemplates(_ context.Context) ([]*domain.PromotionTemplate, error) {
+	return r.filter(func(t *domain.PromotionTemplate) bool { return t.IsActive && inEffect(t) }), nil
+}
+
+func (r *memTemplateRepository) FindActiveAutomatic(_ context.Context) ([]*domain.PromotionTemplate, error) {
+	return r.filter(func(t *domain.PromotionTemplate) bool { return t.IsActive && t.IsAutomatic && inEffect(t) }), nil
+}
+
+func (r *memTemplateRepository) FindActiveByRuleLanguage(_ context.Context, language domain.RuleLanguage) ([]*domain.PromotionTemplate, error) {
+	return r.filter(func(t *domain.PromotionTemplate) bool { return t.IsActive && t.Rule().EffectiveLanguage() == language }), nil
+}
+
+func (r *memTemplateRepository) Create(_ context.Context, template *domain.PromotionTemplate) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	template.ID = int64(len(r.templates) + 1)
+	r.templates = append(r.templates, copyTemplate(template))
+	return nil
+}
+
+func (r *memTemplateRepository) Update(_ context.Context, template *domain.PromotionTemplate) error {
+	for i, t := range r.templates {
+		if t.ID == template.ID {
+			r.templates[i] = copyTemplate(template)
+		}
+	}
+	return nil
+}
+
+func (r *memTemplateRepository) filter(match func(t *domain.PromotionTemplate) bool) []*domain.PromotionTemplate {
+	var templates []*domain.PromotionTemplate
+	for _, t := range r.templates {
+		if match(t) {
+			templates = append(templates, copyTemplate(t))
+		}
+	}
+	return templates
+}
+
+func inEffect(t *domain.PromotionTemplate) bool {
+	now := time.Now()
+	return !t.StartDate.After(now) && !t.EndDate.Before(now)
+}
+
+func copyTemplate(t *domain.PromotionTemplate) *domain.PromotionTemplate {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// memCouponRepository 是基于内存的 domain.CouponRepository，只用于测试。
+type memCouponRepository struct {
+	coupons []*domain.UserCoupon
+}
+
+func (r *memCouponRepository) FindByCode(_ context.Context, code string) (*domain.UserCoupon, error) {
+	for _, c := range r.coupons {
+		if c.CouponCode == code {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memCouponRepository) FindByID(_ context.Context, id int64) (*domain.UserCoupon, error) {
+	for _, c := range r.coupons {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memCouponRepository) FindByUserID(_ context.Context, userID int64) ([]*domain.UserCoupon, error) {
+	var coupons []*domain.UserCoupon
+	for _, c := range r.coupons {
+		if c.UserID == userID {
+			coupons = append(coupons, c)
+		}
+	}
+	return coupons, nil
+}
+
+func (r *memCouponRepository) Save(_ context.Context, coupon *domain.UserCoupon) error {
+	coupon.ID = int64(len(r.coupons) + 1)
+	r.coupons = append(r.coupons, coupon)
+	return nil
+}
+
+func (r *memCouponRepository) Update(context.Context, *domain.UserCoupon) error {
+	return nil
+}
+
+// MoveUnusedToTemplate 替换为修改后的副本而不是原地修改，memUnitOfWork 回滚时恢复切片即可。
+func (r *memCouponRepository) MoveUnusedToTemplate(_ context.Context, fromTemplateID, toTemplateID int64) error {
+	for i, c := range r.coupons {
+		if c.TemplateID == fromTemplateID && c.Status == domain.StatusUnused {
+			moved := *c
+			moved.TemplateID = toTemplateID
+			r.coupons[i] = &moved
+		}
+	}
+	return nil
+}
+
+// memStackingPolicyRepository 是基于内存的 domain.StackingPolicyRepository，只用于测试。
+type memStackingPolicyRepository struct {
+	policies []*domain.StackingPolicy
+}
+
+func (r *memStackingPolicyRepository) FindAll(context.Context) ([]*domain.StackingPolicy, error) {
+	return r.policies, nil
+}
+
+func (r *memStackingPolicyRepository) FindByPromotionType(_ context.Context, promotionType string) (*domain.StackingPolicy, error) {
+	for _, p := range r.policies {
+		if p.PromotionType == promotionType {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memStackingPolicyRepository) Save(_ context.Context, policy *domain.StackingPolicy) error {
+	r.policies = append(r.policies, policy)
+	return nil
+}
+
+func (r *memStackingPolicyRepository) Delete(context.Context, string) error {
+	return nil
+}
+
+// memUnitOfWork 让事务内的仓储直接操作内存仓储，fn 返回错误时恢复执行前的模板和券数据，模拟事务回滚。
+type memUnitOfWork struct {
+	templates *memTemplateRepository
+	coupons   *memCouponRepository
+	stacking  *memStackingPolicyRepository
+}
+
+func (u *memUnitOfWork) Execute(_ context.Context, fn func(domain.RepositoryProvider) error) error {
+	snapshot := make([]*domain.PromotionTemplate, len(u.templates.templates))
+	copy(snapshot, u.templates.templates)
+	coupons := make([]*domain.UserCoupon, len(u.coupons.coupons))
+	copy(coupons, u.coupons.coupons)
+	if err := fn(u); err != nil {
+		u.templates.templates = snapshot
+		u.coupons.coupons = coupons
+		return err
+	}
+	return nil
+}
+
+func (u *memUnitOfWork) Coupons() domain.CouponRepository                  { return u.coupons }
+func (u *memUnitOfWork) Templates() domain.PromotionTemplateRepository     { return u.templates }
+func (u *memUnitOfWork) StackingPolicies() domain.StackingPolicyRepository { return u.stacking }
+
+// newMemService 使用内存仓储和真实的规则引擎、策略创建服务。
+func newMemService(t *testing.T, templates []*domain.PromotionTemplate, coupons []*domain.UserCoupon, opts ...Option) (*promotionServiceImpl, *memTemplateRepository) {
+	t.Helper()
+	templateRepo := &memTemplateRepository{templates: templates}
+	couponRepo := &memCouponRepository{coupons: coupons}
+	stackingRepo := &memStackingPolicyRepository{}
+	uow := &memUnitOfWork{templates: templateRepo, coupons: couponRepo, stacking: stackingRepo}
+	service, err := NewPromotionService(uow, templateRepo, couponRepo, stackingRepo,
+		newTestRuleEngine(t), otel.Tracer("test"), opts...)
+	if err != nil {
+		t.Fatalf("failed to create promotion service: %v", err)
+	}
+	return service.(*promotionServiceImpl), templateRepo
+}
+
+func TestNewPromotionService_RejectsUnsupportedObjectives(t *testing.T) {
+	repo := &memTemplateRepository{}
+	for _, opt := range []Option{WithDefaultObjective("CHEAPEST"), WithChannelObjective("app", "CHEAPEST")} {
+		service, err := NewPromotionService(&memUnitOfWork{templates: repo}, repo, &memCouponRepository{}, &memStackingPolicyRepository{},
+			newTestRuleEngine(t), otel.Tracer("test"), opt)
+		if err == nil || service != nil {
+			t.Errorf("expected an error for an unsupported objective; got %v", service)
+		}
+	}
+}
+
+func TestCalculateBestOffer_AppliesAutomaticPromotionWithoutCoupon(t *testing.T) {
+	start, end := time.Now().AddDate(0, -1, 0), time.Now().AddDate(0, 1, 0)
+	templates := []*domain.PromotionTemplate{
+		// 全场自动满100减10，无需领券，可与其它券叠加
+		{ID: 1, Name: "全场满100减10", PromotionType: domain.PromotionTypePlatformSale, DiscountType: domain.DiscountTypeFixedAmount,
+			DiscountProperties: `{"threshold": 10000, "amount": 1000}`, StartDate: start, EndDate: end,
+			IsAutomatic: true, Priority: 100, IsActive: true},
+		// 需要领取的VIP 9折券
+		{ID: 2, Name: "VIP九折", PromotionType: domain.PromotionTypePlatformSale, RuleDefinition: `fact.User.IsVip`,
+			DiscountType: domain.DiscountTypePercentage, DiscountProperties: `{"percentage": 90}`, StartDate: start, EndDate: end,
+			Priority: 50, IsActive: true},
+		// 已停用的自动促销不参与择优
+		{ID: 3, Name: "已停用的满100减50", PromotionType: domain.PromotionTypePlatformSale, DiscountType: domain.DiscountTypeFixedAmount,
+			DiscountProperties: `{"threshold": 10000, "amount": 5000}`, StartDate: start, EndDate: end, IsAutomatic: true},
+	}
+	coupons := []*domain.UserCoupon{
+		{ID: 1, UserID: 7, CouponCode: "VIP-7", TemplateID: 2, Status: domain.StatusUnused, ExpiryDate: end},
+	}
+	service, _ := newMemService(t, templates, coupons)
+
+	fact := &domain.Fact{
+		User:        domain.UserContext{ID: 8, IsVip: true},
+		Items:       []domain.CartItem{{SKU: "A", Price: 12000, Quantity: 1}},
+		TotalAmount: 12000,
+	}
+	resp, err := service.CalculateBestOffer(context.Background(), fact, OfferOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Amount != 1000 || len(resp.Coupons) != 1 || !resp.Coupons[0].IsAutomatic || resp.Coupons[0].TemplateID != 1 {
+		t.Fatalf("expected only the automatic promotion for a user without coupons; got %+v", resp.OfferPlanResponse)
+	}
+
+	// 持券用户：自动促销先减10元，9折券基于剩余的110元再优惠11元
+	fact.User.ID = 7
+	resp, err = service.CalculateBestOffer(context.Background(), fact, OfferOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Amount != 2100 || len(resp.Coupons) != 2 {
+		t.Fatalf("expected the automatic promotion stacked with the coupon (2100); got %+v", resp.OfferPlanResponse)
+	}
+	if !resp.Coupons[0].IsAutomatic || resp.Coupons[1].CouponCode != "VIP-7" {
+		t.Errorf("expected the automatic promotion followed by coupon VIP-7; got %+v, %+v", resp.Coupons[0], resp.Coupons[1])
+	}
+}
+
+func TestPromotionTemplate_RuleLanguageIsCaseInsensitiveAndInherited(t *testing.T) {
+	service, repo := newMemService(t, nil, nil)
+	ctx := context.Background()
+	jsonRule := `{"fact": "User.IsVip", "operator": "equal", "value": true}`
+
+	created, err := service.CreatePromotionTemplate(ctx, &CreateTemplateRequest{
+		Name: "VIP满100减20", PromotionType: domain.PromotionTypePlatformSale,
+		RuleLanguage: "json", RuleDefinition: jsonRule,
+		DiscountType: string(domain.DiscountTypeFixedAmount), DiscountProperties: `{"threshold": 10000, "amount": 2000}`,
+		StartDate: time.Now(), EndDate: time.Now().AddDate(0, 1, 0),
+	})
+	if err != nil {
+		t.Fatalf("expected a lower-case rule language to be accepted; got %v", err)
+	}
+	if created.RuleLanguage != string(domain.RuleLanguageJSON) {
+		t.Fatalf("expected rule language JSON; got %q", created.RuleLanguage)
+	}
+
+	// 只修改名称和优惠参数时不传规则语言，新版本应沿用 JSON，而不是按 CEL 编译 JSON 规则
+	updated, err := service.UpdatePromotionTemplate(ctx, &UpdateTemplateRequest{
+		TemplateGroupID: created.TemplateGroupID, Name: "VIP满100减30", RuleDefinition: jsonRule,
+		DiscountType: string(domain.DiscountTypeFixedAmount), DiscountProperties: `{"threshold": 10000, "amount": 3000}`,
+		StartDate: time.Now(), EndDate: time.Now().AddDate(0, 1, 0),
+	})
+	if err != nil {
+		t.Fatalf("expected the update to inherit the rule language; got %v", err)
+	}
+	if updated.Version != 2 || updated.RuleLanguage != string(domain.RuleLanguageJSON) {
+		t.Errorf("expected version 2 with rule language JSON; got version %d, %q", updated.Version, updated.RuleLanguage)
+	}
+	if previous, _ := repo.FindByID(ctx, created.ID); previous == nil || previous.IsActive {
+		t.Errorf("expected the previous version to be deactivated; got %+v", previous)
+	}
+}
+
+func TestMigrateRulesToCEL_CreatesNewVersionsInOneTransaction(t *testing.T) {
+	start, end := time.Now().AddDate(0, -1, 0), time.Now().AddDate(0, 1, 0)
+	jsonTemplate := func(id int64, group, definition string) *domain.PromotionTemplate {
+		return &domain.PromotionTemplate{ID: id, TemplateGroupID: group, Version: 1, Name: group, PromotionType: domain.PromotionTypePlatformSale,
+			RuleLanguage: domain.RuleLanguageJSON, RuleDefinition: definition, DiscountType: domain.DiscountTypeFixedAmount,
+			DiscountProperties: `{"threshold": 10000, "amount": 1000}`, StartDate: start, EndDate: end, IsActive: true}
+	}
+	vipRule := `{"fact": "User.IsVip", "operator": "equal", "value": true}`
+	newTemplates := func() []*domain.PromotionTemplate {
+		return []*domain.PromotionTemplate{
+			jsonTemplate(1, "vip", vipRule),
+			jsonTemplate(2, "app", `{"fact": "Environment.Channel", "operator": "in", "value": ["app"]}`),
+			jsonTemplate(3, "broken", `{"fact": "User.Unknown", "operator": "equal", "value": 1}`),
+		}
+	}
+	newCoupons := func() []*domain.UserCoupon {
+		return []*domain.UserCoupon{
+			{ID: 1, UserID: 7, CouponCode: "VIP-UNUSED", TemplateID: 1, Status: domain.StatusUnused, ExpiryDate: end},
+			{ID: 2, UserID: 7, CouponCode: "VIP-USED", TemplateID: 1, Status: domain.StatusUsed, ExpiryDate: end},
+		}
+	}
+	ctx := context.Background()
+
+	// 写入失败时整个迁移回滚，旧版本保持激活
+	service, repo := newMemService(t, newTemplates(), newCoupons())
+	repo.createErr = errors.New("disk full")
+	if _, err := service.MigrateRulesToCEL(ctx, true); err == nil {
+		t.Fatalf("expected the migration to fail")
+	}
+	if len(repo.templates) != 3 {
+		t.Fatalf("expected no new versions after a failed migration; got %d templates", len(repo.templates))
+	}
+	for _, tpl := range repo.templates {
+		if !tpl.IsActive || tpl.RuleLanguage != domain.RuleLanguageJSON {
+			t.Errorf("expected template %d to be rolled back; got active=%v language=%s", tpl.ID, tpl.IsActive, tpl.RuleLanguage)
+		}
+	}
+	if coupon, _ := service.couponRepo.FindByID(ctx, 1); coupon.TemplateID != 1 {
+		t.Errorf("expected the unused coupon to stay on template 1 after a failed migration; got %d", coupon.TemplateID)
+	}
+
+	service, repo = newMemService(t, newTemplates(), newCoupons())
+	resp, err := service.MigrateRulesToCEL(ctx, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Converted != 2 || resp.Failed != 1 {
+		t.Fatalf("expected 2 converted and 1 failed template; got %+v", resp)
+	}
+	for _, item := range resp.Templates[:2] {
+		previous, _ := repo.FindByID(ctx, item.TemplateID)
+		migrated, _ := repo.FindByID(ctx, item.NewTemplateID)
+		if previous == nil || previous.IsActive || previous.RuleLanguage != domain.RuleLanguageJSON || previous.RuleDefinition != item.JSONRule {
+			t.Errorf("expected template %d to keep its JSON rule and be deactivated; got %+v", item.TemplateID, previous)
+		}
+		if migrated == nil || !migrated.IsActive || migrated.Version != 2 || migrated.TemplateGroupID != previous.TemplateGroupID ||
+			migrated.RuleLanguage != domain.RuleLanguageCEL || migrated.RuleDefinition != item.CELExpression {
+			t.Errorf("expected an active CEL version 2 for template %d; got %+v", item.TemplateID, migrated)
+		}
+	}
+	if broken, _ := repo.FindByID(ctx, 3); !broken.IsActive || resp.Templates[2].NewTemplateID != 0 {
+		t.Errorf("expected the unconvertible template to stay untouched; got %+v", broken)
+	}
+
+	// 未使用的券随之改用 CEL 版本，已使用的券保留下单时的版本
+	if unused, _ := service.couponRepo.FindByID(ctx, 1); unused.TemplateID != resp.Templates[0].NewTemplateID {
+		t.Errorf("expected the unused coupon to move to template %d; got %d", resp.Templates[0].NewTemplateID, unused.TemplateID)
+	}
+	if used, _ := service.couponRepo.FindByID(ctx, 2); used.TemplateID != 1 {
+		t.Errorf("expected the used coupon to keep template 1; got %d", used.TemplateID)
+	}
+	offers, err := service.CalculateBestOffer(ctx, &domain.Fact{
+		User:        domain.UserContext{ID: 7, IsVip: true},
+		Items:       []domain.CartItem{{SKU: "A", Price: 12000, Quantity: 1}},
+		TotalAmount: 12000,
+	}, OfferOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(offers.Coupons) != 1 || offers.Coupons[0].CouponCode != "VIP-UNUSED" || offers.Coupons[0].TemplateID != resp.Templates[0].NewTemplateID {
+		t.Errorf("expected the unused coupon to be evaluated with the CEL version; got %+v", offers.OfferPlanResponse)
+	}
+}
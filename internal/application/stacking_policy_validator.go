@@ -0,0 +1,29 @@
+package application
+
+import (
+	"strings"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// validateStackingPolicy 检查叠加规则能否被正确存储和执行。
+// StackableWith 以逗号分隔落库，因此促销类型本身不能包含逗号。
+func validateStackingPolicy(p *domain.StackingPolicy) error {
+	verr := &ValidationError{Message: "invalid stacking policy"}
+
+	if p.PromotionType == "" {
+		verr.add("promotion_type", "promotion type is required")
+	} else if p.PromotionType == domain.StackableWithAny || strings.Contains(p.PromotionType, ",") {
+		verr.add("promotion_type", "invalid promotion type %q", p.PromotionType)
+	}
+	if p.MaxCount < 0 {
+		verr.add("max_count", "max count must not be negative, got %d", p.MaxCount)
+	}
+	for i, t := range p.StackableWith {
+		if t == "" || strings.Contains(t, ",") {
+			verr.add("stackable_with", "invalid promotion type %q at index %d", t, i)
+		}
+	}
+
+	return verr.errOrNil()
+}
@@ -0,0 +1,56 @@
+package application
+
+import (
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// validateTemplate 在模板落库之前检查它能否被正确执行。
+// 规则按其语言用配置的 RuleEngine 编译，参数用对应的策略严格解析，
+// 这样错误会在创建时暴露，而不是在 CalculateBestOffer 中被静默跳过。
+func (s *promotionServiceImpl) validateTemplate(t *domain.PromotionTemplate) error {
+	verr := &ValidationError{Message: "invalid promotion template"}
+
+	if t.Name == "" {
+		verr.add("name", "name is required")
+	}
+	if t.PromotionType == "" {
+		verr.add("promotion_type", "promotion type is required")
+	}
+	if t.StoreID < 0 {
+		verr.add("store_id", "store id must not be negative, got %d", t.StoreID)
+	} else if t.PromotionType == domain.PromotionTypeStoreCoupon && !t.IsStoreScoped() {
+		verr.add("store_id", "store id is required for %s templates", domain.PromotionTypeStoreCoupon)
+	}
+
+	switch t.Rule().EffectiveLanguage() {
+	case domain.RuleLanguageCEL, domain.RuleLanguageJSON:
+		if err := s.ruleEngine.Compile(t.Rule()); err != nil {
+			verr.add("rule_definition", "%v", err)
+		}
+	default:
+		verr.add("rule_language", "unsupported rule language %q, expected %s or %s",
+			t.RuleLanguage, domain.RuleLanguageCEL, domain.RuleLanguageJSON)
+	}
+
+	if t.DiscountType == "" {
+		verr.add("discount_type", "discount type is required")
+	} else if err := s.strategyFty.ValidateProperties(t.DiscountType, t.DiscountProperties); err != nil {
+		verr.add("discount_properties", "%v", err)
+	}
+
+	if t.StartDate.IsZero() {
+		verr.add("start_date", "start date is required")
+	}
+	if t.EndDate.IsZero() {
+		verr.add("end_date", "end date is required")
+	}
+	if !t.StartDate.IsZero() && !t.EndDate.IsZero() && !t.EndDate.After(t.StartDate) {
+		verr.add("end_date", "end date must be after start date")
+	}
+
+	if t.Priority < 0 {
+		verr.add("priority", "priority must not be negative, got %d", t.Priority)
+	}
+
+	return verr.errOrNil()
+}
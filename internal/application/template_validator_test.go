@@ -0,0 +1,107 @@
+// internal/application/template_validator_test.go
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/discount"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/rule"
+)
+
+// newTestRuleEngine 返回与 main.go 相同组合的规则引擎，同时支持 CEL 和 JSON 规则。
+func newTestRuleEngine(t *testing.T) domain.RuleEngine {
+	t.Helper()
+	celEngine, err := rule.NewCelRuleEngine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rule.NewRoutingRuleEngine(map[domain.RuleLanguage]domain.RuleEngine{
+		domain.RuleLanguageCEL:  celEngine,
+		domain.RuleLanguageJSON: rule.NewJSONRuleEngineAdapter(),
+	})
+}
+
+func newValidatingService(t *testing.T) *promotionServiceImpl {
+	t.Helper()
+	return &promotionServiceImpl{ruleEngine: newTestRuleEngine(t), strategyFty: discount.NewStrategyFactory()}
+}
+
+func validTemplate() *domain.PromotionTemplate {
+	return &domain.PromotionTemplate{
+		Name:               "满100减20",
+		PromotionType:      domain.PromotionTypePlatformSale,
+		RuleDefinition:     `fact.User.IsVip`,
+		DiscountType:       domain.DiscountTypeFixedAmount,
+		DiscountProperties: `{"threshold": 10000, "amount": 2000}`,
+		StartDate:          time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:            time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestValidateTemplate_ReportsFieldProblems(t *testing.T) {
+	s := newValidatingService(t)
+
+	tests := []struct {
+		name   string
+		mutate func(t *domain.PromotionTemplate)
+		fields []string // 为 nil 时期望校验通过
+	}{
+		{"valid cel rule", func(*domain.PromotionTemplate) {}, nil},
+		{"valid json rule", func(t *domain.PromotionTemplate) {
+			t.RuleLanguage = domain.RuleLanguageJSON
+			t.RuleDefinition = `{"fact": "User.IsVip", "operator": "equal", "value": true}`
+		}, nil},
+		{"cel syntax error", func(t *domain.PromotionTemplate) {
+			t.RuleDefinition = `fact.User.IsVip &&`
+		}, []string{"rule_definition"}},
+		{"json rule with unknown fact", func(t *domain.PromotionTemplate) {
+			t.RuleLanguage = domain.RuleLanguageJSON
+			t.RuleDefinition = `{"fact": "User.Level", "operator": "equal", "value": 3}`
+		}, []string{"rule_definition"}},
+		{"unsupported rule language", func(t *domain.PromotionTemplate) {
+			t.RuleLanguage = "DROOLS"
+		}, []string{"rule_language"}},
+		{"misspelled discount property", func(t *domain.PromotionTemplate) {
+			t.DiscountProperties = `{"threshhold": 10000, "amount": 2000}`
+		}, []string{"discount_properties"}},
+		{"store coupon without store", func(t *domain.PromotionTemplate) {
+			t.PromotionType = domain.PromotionTypeStoreCoupon
+		}, []string{"store_id"}},
+		{"all problems are reported together", func(t *domain.PromotionTemplate) {
+			t.Name = ""
+			t.EndDate = t.StartDate
+			t.Priority = -1
+		}, []string{"name", "end_date", "priority"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := validTemplate()
+			tt.mutate(template)
+
+			err := s.validateTemplate(template)
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("expected a valid template; got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a *ValidationError; got %v", err)
+			}
+			if len(verr.Problems) != len(tt.fields) {
+				t.Fatalf("expected problems on %v; got %v", tt.fields, verr)
+			}
+			for i, field := range tt.fields {
+				if verr.Problems[i].Field != field {
+					t.Errorf("expected problem %d on %s; got %s", i, field, verr.Problems[i].Field)
+				}
+			}
+		})
+	}
+}
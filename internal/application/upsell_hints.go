@@ -0,0 +1,80 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// maxUpsellHints 是一次返回的凑单提示的最大条数。
+const maxUpsellHints = 3
+
+// nearMiss 是一张当前算不出优惠、但再买一些就能使用的券或自动促销。
+type nearMiss struct {
+	evaluation *couponEvaluation
+	shortfall  domain.Shortfall
+}
+
+// upsellHints 为差一点就能使用的券和自动促销生成凑单提示，如“再买¥23可享「满100减20」”。
+// 只有当前算不出优惠、且策略能给出门槛差额的才会生成提示；差额越小越靠前，最多返回 maxUpsellHints 条。
+func upsellHints(evaluations []*couponEvaluation) []*UpsellHintResponse {
+	misses := make([]nearMiss, 0)
+	seen := make(map[int64]bool)
+	for _, e := range evaluations {
+		if e.candidate == nil || seen[e.template.ID] {
+			continue
+		}
+		offer, err := e.candidate.strategy.Calculate(e.fact, e.template)
+		if err != nil || (offer != nil && offer.Value() > 0) {
+			continue
+		}
+		if shortfall := shortfallOf(e); !shortfall.IsZero() {
+			seen[e.template.ID] = true
+			misses = append(misses, nearMiss{evaluation: e, shortfall: shortfall})
+		}
+	}
+
+	// 金额门槛在前、件数门槛在后，同类按差额从小到大，差额相同时优先级高者在前
+	sort.SliceStable(misses, func(i, j int) bool {
+		a, b := misses[i], misses[j]
+		if (a.shortfall.Amount > 0) != (b.shortfall.Amount > 0) {
+			return a.shortfall.Amount > 0
+		}
+		if a.shortfall.Amount != b.shortfall.Amount {
+			return a.shortfall.Amount < b.shortfall.Amount
+		}
+		if a.shortfall.Quantity != b.shortfall.Quantity {
+			return a.shortfall.Quantity < b.shortfall.Quantity
+		}
+		if a.evaluation.template.Priority != b.evaluation.template.Priority {
+			return a.evaluation.template.Priority > b.evaluation.template.Priority
+		}
+		return a.evaluation.template.ID < b.evaluation.template.ID
+	})
+	if len(misses) > maxUpsellHints {
+		misses = misses[:maxUpsellHints]
+	}
+
+	hints := make([]*UpsellHintResponse, 0, len(misses))
+	for _, m := range misses {
+		hints = append(hints, toUpsellHintResponse(m))
+	}
+	return hints
+}
+
+// toUpsellHintResponse 将凑单机会转换为DTO
+func toUpsellHintResponse(m nearMiss) *UpsellHintResponse {
+	template := m.evaluation.template
+	hint := &UpsellHintResponse{
+		TemplateID:   template.ID,
+		TemplateName: template.Name,
+		IsAutomatic:  m.evaluation.coupon == nil,
+		Shortfall:    toShortfallResponse(m.shortfall),
+		Message:      fmt.Sprintf("再买%s可享「%s」", describeShortfall(m.shortfall), template.Name),
+	}
+	if m.evaluation.coupon != nil {
+		hint.CouponCode = m.evaluation.coupon.CouponCode
+	}
+	return hint
+}
@@ -0,0 +1,46 @@
+// promotion-service/internal/domain/allocation.go
+package domain
+
+import "sort"
+
+// Prorate 将 amount 按 weights 中各行的 Amount 占比分摊到这些行上。
+// 每行先向下取整到分，剩余的分按余数从大到小（余数相同按行号从小到大）逐分补齐，
+// 这样分摊结果之和恰好等于 amount，且同一输入总是得到同一结果。
+// 所有需要拆分优惠金额的地方都应使用它，避免各处的尾差处理不一致。
+// 没有可分摊的行（weights 为空或金额都为0）时返回 nil，见 DiscountApplication.Allocations 的说明。
+func Prorate(amount int64, weights []LineAllocation) []LineAllocation {
+	var total int64
+	for _, w := range weights {
+		total += w.Amount
+	}
+	if amount <= 0 || total <= 0 {
+		return nil
+	}
+
+	allocations := make([]LineAllocation, len(weights))
+	remainders := make([]int64, len(weights))
+	var allocated int64
+	for i, w := range weights {
+		share := amount * w.Amount / total
+		allocations[i] = LineAllocation{LineIndex: w.LineIndex, SKU: w.SKU, Amount: share}
+		remainders[i] = amount * w.Amount % total
+		allocated += share
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if remainders[order[a]] != remainders[order[b]] {
+			return remainders[order[a]] > remainders[order[b]]
+		}
+		return weights[order[a]].LineIndex < weights[order[b]].LineIndex
+	})
+	for i := 0; allocated < amount; i++ {
+		allocations[order[i%len(order)]].Amount++
+		allocated++
+	}
+
+	return allocations
+}
@@ -1,11 +1,67 @@
 // promotion-service/internal/domain/discount.go
 package domain
 
+// DiscountLayer 定义了优惠作用的层级。
+type DiscountLayer string
+
+const (
+	DiscountLayerGoods    DiscountLayer = "GOODS"    // 作用于商品金额
+	DiscountLayerShipping DiscountLayer = "SHIPPING" // 只作用于运费
+)
+
 // DiscountApplication 表示一次优惠计算的具体结果。
 type DiscountApplication struct {
-	Amount       int64  // 优惠的总金额
-	StrategyName string // 应用的策略名称，用于追踪和调试
-	Description  string // 优惠的描述，可用于向用户展示
+	Amount       int64         // 优惠的总金额
+	StrategyName string        // 应用的策略名称，用于追踪和调试
+	Description  string        // 优惠的描述，可用于向用户展示
+	Layer        DiscountLayer // 优惠作用的层级（商品或运费）
+
+	// Reward 是非金额类的权益（积分、返现、赠品），它不减少应付金额，
+	// 因此 Amount 通常为0；择优时按 Reward.MonetaryValue 折算后与其它优惠比较。
+	Reward *Reward
+
+	// Allocations 是优惠金额在各购物车行上的分摊明细，各行金额之和等于 Amount。
+	// 订单服务依赖它计算部分退货时的退款金额，因此分摊结果必须是确定性的。
+	// 例外：调用方只传 TotalAmount、没有商品明细（Items 为空）时无行可分摊，Allocations 为空，
+	// 优惠只能按整单处理；运费层的优惠不涉及商品行，Allocations 同样为空。
+	Allocations []LineAllocation
+}
+
+// Value 返回本次优惠折算后的总价值，即优惠金额加上权益的等价金额，用于择优排序。
+func (d *DiscountApplication) Value() int64 {
+	if d.Reward == nil {
+		return d.Amount
+	}
+	return d.Amount + d.Reward.MonetaryValue
+}
+
+// RewardType 定义了非金额类权益的类型。
+type RewardType string
+
+const (
+	RewardTypePoints   RewardType = "POINTS"   // 赠送积分
+	RewardTypeCashback RewardType = "CASHBACK" // 下单后返现
+	RewardTypeGift     RewardType = "GIFT"     // 赠品
+)
+
+// Reward 描述一次优惠所发放的非金额类权益。
+type Reward struct {
+	Type         RewardType
+	Points       int64  // 赠送的积分数
+	Cashback     int64  // 返现金额（单位：分），订单完成后发放
+	GiftSKU      string // 赠品SKU
+	GiftQuantity int32  // 赠品数量
+
+	// MonetaryValue 是该权益折算的等价金额（单位：分），由模板配置决定折算方式，
+	// 只用于与其它优惠比较，不代表实际减免的金额。
+	MonetaryValue int64
+}
+
+// LineAllocation 描述分摊到某一购物车行上的优惠金额。
+type LineAllocation struct {
+	LineIndex int    // 对应 Fact.Items 中的下标
+	SKU       string // 该行商品的SKU，冗余存储便于核对
+	Amount    int64  // 分摊到该行的优惠金额（单位：分）
 }
 
 // DiscountStrategy 定义了优惠计算策略的接口。
@@ -17,3 +73,21 @@ type DiscountStrategy interface {
 	// 返回值: *DiscountApplication 描述了本次优惠计算的结果, error 计算过程中的错误
 	Calculate(fact Fact, template *PromotionTemplate) (*DiscountApplication, error)
 }
+
+// Shortfall 描述距离满足优惠门槛还差多少，金额门槛与件数门槛按策略类型二选一。
+type Shortfall struct {
+	Amount   int64 // 还差的金额（单位：分）
+	Quantity int32 // 还差的件数
+}
+
+// IsZero 判断是否已经满足门槛。
+func (s Shortfall) IsZero() bool {
+	return s.Amount <= 0 && s.Quantity <= 0
+}
+
+// ShortfallStrategy 是 DiscountStrategy 的可选扩展，由带金额或件数门槛的策略实现。
+// 结算页和凑单提示据此告诉用户“还差多少可用”，而不必理解每种策略的参数结构。
+type ShortfallStrategy interface {
+	// Shortfall 返回距离最低门槛还差的金额或件数，已达到门槛时返回零值
+	Shortfall(fact Fact, template *PromotionTemplate) (Shortfall, error)
+}
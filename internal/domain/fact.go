@@ -10,6 +10,21 @@ type CartItem struct {
 	Quantity int32  `json:"Quantity"`
 	Category string `json:"Category"` // 商品品类
 	Brand    string `json:"Brand"`    // 商品品牌
+	StoreID  int64  `json:"StoreID"`  // 所属店铺ID, 多商家购物车中用于限定店铺券的作用范围
+
+	// Discount 是已经分摊到该行的优惠金额（单位：分）。
+	// 多张券叠加时由服务层在计算下一张券之前填充，属于服务内部状态，
+	// 不参与 JSON 编解码，调用方无法借此传入预先打过折的商品行。
+	Discount int64 `json:"-"`
+}
+
+// Subtotal 返回该行扣除已分摊优惠后的应付金额。
+func (i CartItem) Subtotal() int64 {
+	subtotal := i.Price*int64(i.Quantity) - i.Discount
+	if subtotal < 0 {
+		return 0
+	}
+	return subtotal
 }
 
 // UserContext 代表当前的用户信息
@@ -25,6 +40,12 @@ type EnvironmentContext struct {
 	Channel   string    `json:"Channel"`   // 渠道, e.g., "app", "mini_program"
 }
 
+// ShippingContext 代表订单的配送信息
+type ShippingContext struct {
+	Fee    int64  `json:"Fee"`    // 运费（单位：分）
+	Region string `json:"Region"` // 收货地区, e.g., "Shanghai", "Xinjiang"
+}
+
 // Fact 是规则引擎和优惠计算策略所需的所有上下文信息的集合。
 // 它是一个高度结构化的数据对象，作为评估过程的唯一输入。
 // 这种设计将计算逻辑与数据来源完全解耦，极大地提高了系统的可测试性和可扩展性。
@@ -32,7 +53,52 @@ type Fact struct {
 	User        UserContext        `json:"User"`
 	Items       []CartItem         `json:"Items"`
 	Environment EnvironmentContext `json:"Environment"`
+	Shipping    ShippingContext    `json:"Shipping"`
 
 	// 派生字段，在服务层预先计算，以简化规则逻辑
 	TotalAmount int64 `json:"TotalAmount"` // 购物车总金额
 }
+
+// ApplyDiscount 返回扣除一次优惠后的新 Fact，原 Fact 不会被修改。
+// 多张券叠加时，下一张券基于剩余的应付金额计算：商品层优惠会减少 TotalAmount 并累加到各行的 Discount 上，
+// 运费层优惠只减少运费。
+func (f Fact) ApplyDiscount(d *DiscountApplication) Fact {
+	next := f
+	if d == nil || d.Amount <= 0 {
+		return next
+	}
+
+	if d.Layer == DiscountLayerShipping {
+		next.Shipping.Fee -= d.Amount
+		return next
+	}
+
+	next.TotalAmount -= d.Amount
+	next.Items = make([]CartItem, len(f.Items))
+	copy(next.Items, f.Items)
+	for _, a := range d.Allocations {
+		if a.LineIndex >= 0 && a.LineIndex < len(next.Items) {
+			next.Items[a.LineIndex].Discount += a.Amount
+		}
+	}
+	return next
+}
+
+// ForStore 返回只包含指定店铺商品的子 Fact，以及子 Fact 中每一行在原 Fact 中的下标。
+// 子 Fact 的 TotalAmount 是该店铺商品扣除已分摊优惠后的合计，用户、环境和配送信息保持不变。
+func (f Fact) ForStore(storeID int64) (Fact, []int) {
+	sub := f
+	sub.Items = nil
+	sub.TotalAmount = 0
+
+	var lineIndexes []int
+	for idx, item := range f.Items {
+		if item.StoreID != storeID {
+			continue
+		}
+		sub.Items = append(sub.Items, item)
+		sub.TotalAmount += item.Subtotal()
+		lineIndexes = append(lineIndexes, idx)
+	}
+	return sub, lineIndexes
+}
@@ -0,0 +1,18 @@
+// internal/domain/fact_test.go
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCartItem_DiscountIsNotDecodedFromRequests(t *testing.T) {
+	var fact Fact
+	body := `{"Items": [{"SKU": "A", "Price": 10000, "Quantity": 1, "Discount": 9000}], "TotalAmount": 10000}`
+	if err := json.Unmarshal([]byte(body), &fact); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fact.Items[0].Discount != 0 || fact.Items[0].Subtotal() != 10000 {
+		t.Errorf("expected the client supplied discount to be ignored; got %+v", fact.Items[0])
+	}
+}
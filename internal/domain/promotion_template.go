@@ -9,9 +9,42 @@ type DiscountType string
 const (
 	DiscountTypeFixedAmount DiscountType = "FIXED_AMOUNT" // 满减/立减
 	DiscountTypePercentage  DiscountType = "PERCENTAGE"   // 折扣
-	// 未来可以轻松扩展, e.g., DiscountTypeFreebie, DiscountTypePoints
+	// DiscountTypeTieredFixedAmount 阶梯满减，如 满100减10 / 满200减30 / 满500减100
+	DiscountTypeTieredFixedAmount DiscountType = "TIERED_FIXED_AMOUNT"
+	// DiscountTypeEveryFixedAmount 每满减，如 每满100减10，最高减50
+	DiscountTypeEveryFixedAmount DiscountType = "EVERY_FIXED_AMOUNT"
+	// DiscountTypeBuyXGetY 买M赠N，每买M件最便宜的N件免费或打折
+	DiscountTypeBuyXGetY DiscountType = "BUY_X_GET_Y"
+	// DiscountTypeNthItem 第N件优惠，如 第二件半价 / 第三件免费
+	DiscountTypeNthItem DiscountType = "NTH_ITEM"
+	// DiscountTypeBundlePrice 组合一口价，如 指定商品任选3件99元
+	DiscountTypeBundlePrice DiscountType = "BUNDLE_PRICE"
+	// DiscountTypeFreeShipping 包邮，免除全部运费
+	DiscountTypeFreeShipping DiscountType = "FREE_SHIPPING"
+	// DiscountTypeShippingDiscount 运费减免，最多减免运费金额
+	DiscountTypeShippingDiscount DiscountType = "SHIPPING_DISCOUNT"
+	// DiscountTypePoints 赠送积分，不减少应付金额
+	DiscountTypePoints DiscountType = "POINTS"
+	// DiscountTypeCashback 下单返现，不减少应付金额
+	DiscountTypeCashback DiscountType = "CASHBACK"
+	// DiscountTypeGift 赠品，不减少应付金额
+	DiscountTypeGift DiscountType = "GIFT"
+	// DiscountTypeFormula 公式，用CEL表达式直接计算优惠金额
+	DiscountTypeFormula DiscountType = "FORMULA"
+	// 未来可以轻松扩展
 )
 
+// Layer 返回该优惠类型作用的层级。
+// 运费券只减免运费，与商品券分属不同层级，在择优时互不竞争。
+func (t DiscountType) Layer() DiscountLayer {
+	switch t {
+	case DiscountTypeFreeShipping, DiscountTypeShippingDiscount:
+		return DiscountLayerShipping
+	default:
+		return DiscountLayerGoods
+	}
+}
+
 // PromotionTemplate 是优惠的核心定义，它是一个不可变对象。
 // 任何对模板的修改都应该创建一个新的版本，而不是在原地更新。
 // 这个模型现在包含了所有业务逻辑和规则评估所需的完整字段。
@@ -22,12 +55,20 @@ type PromotionTemplate struct {
 	Name            string // e.g., "双十一超级满减券"
 	Description     string // 详细描述
 	PromotionType   string // [新增] 促销类型, 如 'STORE_COUPON', 'PLATFORM_SALE' [cite: 179]
+	StoreID         int64  // 所属店铺ID, 非0时只作用于该店铺的商品, 0 表示平台级促销
 
 	// --- 核心规则与策略字段 ---
-	// RuleDefinition 是一个JSON字符串，定义了此优惠的适用条件 (LHS)。
+	// RuleLanguage 是 RuleDefinition 使用的语言（CEL 或 JSON），为空时视为 CEL。
+	RuleLanguage RuleLanguage
+
+	// RuleDefinition 定义了此优惠的适用条件 (LHS)，按 RuleLanguage 解释。
 	// 它将被传递给RuleEngine进行评估。
 	RuleDefinition string
 
+	// RuleHint 是面向用户的规则说明，如 "仅限VIP"、"仅限新用户"。
+	// 规则不满足时，结算页用它告诉用户这张券为什么不可用。
+	RuleHint string
+
 	// DiscountType 标识了优惠的计算方式 (RHS)。
 	// 它将用于策略工厂来获取正确的DiscountStrategy。
 	DiscountType DiscountType
@@ -35,12 +76,25 @@ type PromotionTemplate struct {
 	// DiscountProperties 是一个JSON字符串，存储了具体策略所需的参数。
 	// 例如，对于满减券是 {"threshold": 20000, "amount": 2000}
 	// 对于折扣券是 {"percentage": 88, "ceiling": 5000} (88折，最多优惠50元)
+	// 对于阶梯满减是 {"tiers": [{"threshold": 10000, "amount": 1000}, {"threshold": 20000, "amount": 3000}]}
+	// 对于每满减是 {"step": 10000, "amount": 1000, "ceiling": 5000} (每满100减10，最高减50)
+	// 对于买M赠N是 {"buy_quantity": 3, "get_quantity": 1, "percentage": 0, "skus": ["SKU001"]} (每买3件最便宜的1件免费)
+	// 满减、折扣等策略都可以附加商品范围, 如 {"percentage": 90, "categories": ["Electronics"], "exclude_brands": ["Apple"]}
+	// 对于第N件优惠是 {"position_percentages": [100, 50], "per_sku": true} (同款第二件半价)
+	// 对于组合一口价是 {"bundle_size": 3, "bundle_price": 9900, "skus": ["SKU001", "SKU002"]} (任选3件99元)
+	// 对于包邮是 {"threshold": 9900, "exclude_regions": ["Xinjiang", "Tibet"]} (满99包邮，偏远地区除外)
+	// 对于运费减免是 {"threshold": 0, "amount": 600, "regions": ["Shanghai"]} (上海地区运费减6元)
+	// 对于赠送积分是 {"threshold": 10000, "points": 500, "point_value": 1} (满100送500积分，每积分按1分折算)
+	// 对于下单返现是 {"threshold": 10000, "amount": 1000, "value_rate": 80} (满100返10元，择优时按8折折算)
+	// 对于赠品是 {"threshold": 20000, "gift_sku": "GIFT001", "gift_quantity": 1, "gift_value": 3000} (满200赠价值30元的赠品)
+	// 对于公式是 {"expression": "min(fact.TotalAmount / 10, 3000)"} (立减10%，最高30元)
 	DiscountProperties string
 
 	// --- 生命周期与元数据 ---
 	StartDate   time.Time // [新增] 活动生效时间 [cite: 182]
 	EndDate     time.Time // [新增] 活动失效时间 [cite: 182]
 	IsExclusive bool      // [新增] 是否与其它优惠互斥 [cite: 183]
+	IsAutomatic bool      // 是否为自动促销, 自动促销无需领券, 对所有满足规则的购物车生效
 	Priority    int       // [新增] 优先级, 数字越大优先级越高 [cite: 184]
 	IsActive    bool      // [新增] 当前版本是否激活 [cite: 185]
 
@@ -49,6 +103,16 @@ type PromotionTemplate struct {
 	UpdatedAt time.Time
 }
 
+// IsStoreScoped 判断模板是否只作用于某个店铺的商品。
+func (pt *PromotionTemplate) IsStoreScoped() bool {
+	return pt.StoreID != 0
+}
+
+// Rule 返回模板的适用规则。
+func (pt *PromotionTemplate) Rule() PromotionRule {
+	return PromotionRule{Language: pt.RuleLanguage, Definition: pt.RuleDefinition}
+}
+
 // IsAvailable 检查模板在当前时间是否有效。
 func (pt *PromotionTemplate) IsAvailable() bool {
 	now := time.Now()
@@ -10,6 +10,8 @@ type CouponRepository interface {
 	FindByUserID(ctx context.Context, userID int64) ([]*UserCoupon, error)
 	Save(ctx context.Context, coupon *UserCoupon) error
 	Update(ctx context.Context, coupon *UserCoupon) error
+	// MoveUnusedToTemplate 将关联到 fromTemplateID 的未使用券改为关联到 toTemplateID，用于不改变优惠内容的版本升级
+	MoveUnusedToTemplate(ctx context.Context, fromTemplateID, toTemplateID int64) error
 }
 
 // PromotionTemplateRepository 定义了促销模板的持久化接口
@@ -24,8 +26,24 @@ type PromotionTemplateRepository interface {
 	FindActiveByGroupID(ctx context.Context, groupID string) (*PromotionTemplate, error)
 	// FindAllActiveTemplates 获取所有激活的模板，用于后续筛选
 	FindAllActiveTemplates(ctx context.Context) ([]*PromotionTemplate, error)
+	// FindActiveAutomatic 获取所有激活且正在活动期内的自动促销
+	FindActiveAutomatic(ctx context.Context) ([]*PromotionTemplate, error)
+	// FindActiveByRuleLanguage 获取所有使用指定规则语言的激活版本，不论活动是否已开始或已结束
+	FindActiveByRuleLanguage(ctx context.Context, language RuleLanguage) ([]*PromotionTemplate, error)
 	// Create 创建一个新的模板
 	Create(ctx context.Context, template *PromotionTemplate) error
 	// Update 更新一个模板 (通常是状态)
 	Update(ctx context.Context, template *PromotionTemplate) error
 }
+
+// StackingPolicyRepository 定义了促销类型叠加规则的持久化接口
+type StackingPolicyRepository interface {
+	// FindAll 获取所有叠加规则
+	FindAll(ctx context.Context) ([]*StackingPolicy, error)
+	// FindByPromotionType 获取指定促销类型的叠加规则，不存在时返回 nil
+	FindByPromotionType(ctx context.Context, promotionType string) (*StackingPolicy, error)
+	// Save 创建或覆盖指定促销类型的叠加规则
+	Save(ctx context.Context, policy *StackingPolicy) error
+	// Delete 删除指定促销类型的叠加规则
+	Delete(ctx context.Context, promotionType string) error
+}
@@ -1,24 +1,58 @@
 // promotion-service/internal/domain/rule.go
 package domain
 
+import "strings"
+
+// RuleLanguage 标识规则定义所使用的语言。
+type RuleLanguage string
+
+const (
+	RuleLanguageCEL  RuleLanguage = "CEL"  // CEL 表达式，如 'fact.User.IsVip && fact.TotalAmount >= 10000'，便于工程师编写
+	RuleLanguageJSON RuleLanguage = "JSON" // JSON 条件树，如 '{"all": [{"fact": "User.IsVip", "operator": "equal", "value": true}]}'，由运营工具生成
+)
+
+// DefaultRuleLanguage 是未指定语言的规则所使用的语言，兼容引入多语言之前的模板。
+const DefaultRuleLanguage = RuleLanguageCEL
+
+// ParseRuleLanguage 将外部传入的语言名称转换为 RuleLanguage，大小写不敏感，例如 "json" 解析为 RuleLanguageJSON。
+// 无法识别的名称按大写原样返回，由模板校验报告。
+func ParseRuleLanguage(name string) RuleLanguage {
+	return RuleLanguage(strings.ToUpper(strings.TrimSpace(name)))
+}
+
 // RuleEngine 代表一个规则评估引擎的接口。
 // 它的职责是：根据给定的规则定义（LHS）和事实（Fact），判断条件是否满足。
 type RuleEngine interface {
 	// Evaluate 执行规则评估
-	// ruleDefinition: 规则的文本表示，例如一个JSON字符串
+	// rule: 规则的语言和文本表示
 	// fact: 包含所有上下文信息的事实对象
 	// 返回值: bool 代表是否匹配，error 代表评估过程中是否出错
-	Evaluate(ruleDefinition string, fact Fact) (bool, error)
+	Evaluate(rule PromotionRule, fact Fact) (bool, error)
+
+	// Compile 只编译和检查规则，不执行评估
+	// 用于在保存模板前发现语法错误，而不是等到计算优惠时才静默跳过
+	Compile(rule PromotionRule) error
 }
 
 // PromotionRule 代表一个完整的促销规则。
 // 它封装了规则的定义，并利用RuleEngine来执行评估。
 type PromotionRule struct {
-	// 规则的JSON定义，描述了优惠适用的所有条件 (LHS)。
+	// Language 是规则定义使用的语言，为空时视为 DefaultRuleLanguage。
+	Language RuleLanguage
+
+	// 规则的定义，描述了优惠适用的所有条件 (LHS)。
 	// 例如：'{"all": [{"fact": "user.isVip", "operator": "equal", "value": true}]}'
 	Definition string
 }
 
+// EffectiveLanguage 返回规则实际使用的语言。
+func (r PromotionRule) EffectiveLanguage() RuleLanguage {
+	if r.Language == "" {
+		return DefaultRuleLanguage
+	}
+	return r.Language
+}
+
 // IsSatisfied by a given fact.
 func (r *PromotionRule) IsSatisfied(engine RuleEngine, fact Fact) (bool, error) {
 	// 如果规则定义为空，我们认为它无条件满足。
@@ -26,5 +60,5 @@ func (r *PromotionRule) IsSatisfied(engine RuleEngine, fact Fact) (bool, error)
 	if r.Definition == "" {
 		return true, nil
 	}
-	return engine.Evaluate(r.Definition, fact)
+	return engine.Evaluate(*r, fact)
 }
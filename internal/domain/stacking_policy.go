@@ -0,0 +1,74 @@
+// promotion-service/internal/domain/stacking_policy.go
+package domain
+
+import "time"
+
+// 常用的促销类型。PromotionType 是开放的字符串，这里只列出内置的取值。
+const (
+	PromotionTypeStoreCoupon  = "STORE_COUPON"  // 店铺券
+	PromotionTypePlatformSale = "PLATFORM_SALE" // 平台活动
+)
+
+// StackableWithAny 出现在 StackableWith 中时，表示可以与任意其它促销类型叠加。
+const StackableWithAny = "*"
+
+// StackingPolicy 定义了一种促销类型在一笔订单中的叠加规则。
+// 例如 {PromotionType: "STORE_COUPON", MaxCount: 1, StackableWith: ["PLATFORM_SALE"]}
+// 表示店铺券最多使用一张，且只能与平台活动叠加。
+type StackingPolicy struct {
+	ID            int64
+	PromotionType string   // 该策略约束的促销类型
+	MaxCount      int      // 同类型最多可同时使用的张数, 0 表示不限制
+	StackableWith []string // 可以与之叠加的其它促销类型, "*" 表示任意类型
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// allowsCount 判断同类型已经使用 count 张时能否再加一张。
+func (p *StackingPolicy) allowsCount(count int) bool {
+	return p.MaxCount <= 0 || count < p.MaxCount
+}
+
+// allowsType 判断该类型能否与另一种促销类型叠加。
+func (p *StackingPolicy) allowsType(other string) bool {
+	for _, t := range p.StackableWith {
+		if t == other || t == StackableWithAny {
+			return true
+		}
+	}
+	return false
+}
+
+// StackingPolicies 是按促销类型索引的叠加规则集合。
+// 没有配置策略的促销类型不受额外限制，只遵循模板自身的 IsExclusive。
+type StackingPolicies map[string]*StackingPolicy
+
+// NewStackingPolicies 将策略列表按促销类型建立索引。
+func NewStackingPolicies(policies []*StackingPolicy) StackingPolicies {
+	set := make(StackingPolicies, len(policies))
+	for _, p := range policies {
+		set[p.PromotionType] = p
+	}
+	return set
+}
+
+// CanStack 判断一张 promotionType 类型的券能否加入已选券的组合，chosen 是组合中每张券的促销类型。
+// 不同类型之间需要双方的策略都允许叠加（未配置策略的一方视为允许）。
+func (s StackingPolicies) CanStack(chosen []string, promotionType string) bool {
+	own := s[promotionType]
+	sameType := 0
+	for _, t := range chosen {
+		if t == promotionType {
+			sameType++
+			continue
+		}
+		if own != nil && !own.allowsType(t) {
+			return false
+		}
+		if other := s[t]; other != nil && !other.allowsType(promotionType) {
+			return false
+		}
+	}
+	return own == nil || own.allowsCount(sameType)
+}
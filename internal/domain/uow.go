@@ -16,4 +16,5 @@ type UnitOfWork interface {
 type RepositoryProvider interface {
 	Coupons() CouponRepository
 	Templates() PromotionTemplateRepository
+	StackingPolicies() StackingPolicyRepository
 }
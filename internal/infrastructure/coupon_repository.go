@@ -58,3 +58,9 @@ func (r *gormCouponRepository) Update(ctx context.Context, coupon *domain.UserCo
 	model := toGormUserCoupon(coupon)
 	return r.db.WithContext(ctx).Save(model).Error
 }
+
+func (r *gormCouponRepository) MoveUnusedToTemplate(ctx context.Context, fromTemplateID, toTemplateID int64) error {
+	return r.db.WithContext(ctx).Model(&UserCouponModel{}).
+		Where("template_id = ? AND status = ?", fromTemplateID, domain.StatusUnused).
+		Update("template_id", toTemplateID).Error
+}
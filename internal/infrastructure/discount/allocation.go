@@ -0,0 +1,78 @@
+// promotion-service/internal/infrastructure/discount/allocation.go
+package discount
+
+import (
+	"sort"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// eligibleLines 返回适用范围内每一行扣除已分摊优惠后的金额，作为按比例分摊的基数。
+func eligibleLines(items []domain.CartItem, scope ItemScope) []domain.LineAllocation {
+	lines := make([]domain.LineAllocation, 0, len(items))
+	for i, item := range items {
+		if !scope.Matches(item) {
+			continue
+		}
+		amount := item.Subtotal()
+		if amount <= 0 {
+			continue
+		}
+		lines = append(lines, domain.LineAllocation{LineIndex: i, SKU: item.SKU, Amount: amount})
+	}
+	return lines
+}
+
+// allocateProportionally 将优惠金额按适用范围内各行的金额比例分摊。
+func allocateProportionally(amount int64, fact domain.Fact, scope ItemScope) []domain.LineAllocation {
+	return domain.Prorate(amount, eligibleLines(fact.Items, scope))
+}
+
+// unitAllocator 汇总按件计算出的优惠，最终按行输出分摊明细。
+type unitAllocator struct {
+	items   []domain.CartItem
+	perLine map[int]int64
+}
+
+func newUnitAllocator(items []domain.CartItem) *unitAllocator {
+	return &unitAllocator{items: items, perLine: make(map[int]int64)}
+}
+
+// add 记录一段商品中的 count 件各获得 amount 的优惠。
+func (a *unitAllocator) add(run unitRun, count int64, amount int64) {
+	a.addToLine(run.LineIndex, count*amount)
+}
+
+// addToLine 将优惠直接记到某一购物车行上，每行累计的优惠不超过该行扣除已分摊优惠后的小计。
+func (a *unitAllocator) addToLine(lineIndex int, amount int64) {
+	if remaining := a.items[lineIndex].Subtotal() - a.perLine[lineIndex]; amount > remaining {
+		amount = remaining
+	}
+	if amount > 0 {
+		a.perLine[lineIndex] += amount
+	}
+}
+
+// total 返回已记录的优惠总额。
+func (a *unitAllocator) total() int64 {
+	var sum int64
+	for _, amount := range a.perLine {
+		sum += amount
+	}
+	return sum
+}
+
+// allocations 按行号顺序输出分摊明细。
+func (a *unitAllocator) allocations() []domain.LineAllocation {
+	if len(a.perLine) == 0 {
+		return nil
+	}
+	allocations := make([]domain.LineAllocation, 0, len(a.perLine))
+	for lineIndex, amount := range a.perLine {
+		allocations = append(allocations, domain.LineAllocation{LineIndex: lineIndex, SKU: a.items[lineIndex].SKU, Amount: amount})
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].LineIndex < allocations[j].LineIndex
+	})
+	return allocations
+}
@@ -0,0 +1,108 @@
+// promotion-service/internal/infrastructure/discount/bundle_price_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeBundlePrice,
+		Name:          "组合一口价",
+		Strategy:      &BundlePriceStrategy{},
+		NewProperties: func() Properties { return &BundlePriceStrategyProperties{} },
+	})
+}
+
+// BundlePriceStrategyProperties 定义了组合一口价策略所需的参数结构。
+// 例如 {"bundle_size": 3, "bundle_price": 9900, "skus": ["A", "B", "C", "D"]} 代表“指定商品任选3件99元”。
+type BundlePriceStrategyProperties struct {
+	ItemScope
+	BundleSize  int32 `json:"bundle_size" desc:"每组的件数（N）"`
+	BundlePrice int64 `json:"bundle_price" desc:"每组的一口价（单位：分）"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *BundlePriceStrategyProperties) Validate() error {
+	if p.BundleSize <= 0 {
+		return fmt.Errorf("invalid bundle size: %d", p.BundleSize)
+	}
+	if p.BundlePrice < 0 {
+		return fmt.Errorf("invalid bundle price: %d", p.BundlePrice)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口，门槛为凑满一组的件数。
+func (p *BundlePriceStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, quantity: p.BundleSize}
+}
+
+// BundlePriceStrategy 实现了 domain.DiscountStrategy 接口，用于处理“N件X元”的组合一口价优惠。
+// 它优先把最贵的商品凑成组，尽可能多地成组，每组的优惠为组内商品应付金额之和与一口价的差额。
+type BundlePriceStrategy struct {
+	thresholdShortfall
+}
+
+func (s *BundlePriceStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props BundlePriceStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle price properties: %w", err)
+	}
+
+	// runs 已按单价降序排列，依次切分即可让最贵的商品优先成组
+	cursor := &unitCursor{runs: expandUnits(fact.Items, props.ItemScope)}
+	size := int64(props.BundleSize)
+	allocator := newUnitAllocator(fact.Items)
+	for {
+		// 同一段内能凑满的组完全相同，一次性计算，不必逐组切分
+		if remaining := cursor.remainingInRun(); remaining >= size {
+			run := cursor.runs[cursor.index]
+			saving := run.Price*size - props.BundlePrice
+			if saving <= 0 {
+				// 商品已按单价降序，后面的组只会更便宜，不会再产生优惠
+				break
+			}
+			bundles := remaining / size
+			allocator.add(run, bundles, saving)
+			if cursor.offset += bundles * size; cursor.offset == run.Count {
+				cursor.index, cursor.offset = cursor.index+1, 0
+			}
+			continue
+		}
+
+		// 跨段的组，每组至少取完一段，组数不超过段数
+		bundle, ok := cursor.take(size)
+		if !ok {
+			break
+		}
+		lines := make([]domain.LineAllocation, 0, len(bundle))
+		var originalPrice int64
+		for _, run := range bundle {
+			originalPrice += run.Price * run.Count
+			lines = append(lines, domain.LineAllocation{LineIndex: run.LineIndex, SKU: run.SKU, Amount: run.Price * run.Count})
+		}
+		if originalPrice <= props.BundlePrice {
+			break
+		}
+
+		// 每组的差额在组内各段商品之间按金额比例分摊
+		for _, a := range domain.Prorate(originalPrice-props.BundlePrice, lines) {
+			allocator.addToLine(a.LineIndex, a.Amount)
+		}
+	}
+
+	discountAmount := allocator.total()
+	if discountAmount == 0 {
+		return &domain.DiscountApplication{Amount: 0}, nil // 件数不足或应付金额不高于一口价，不优惠
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       discountAmount,
+		StrategyName: "BundlePriceStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Description:  props.describe(fmt.Sprintf("任选%d件%d.%02d元", props.BundleSize, props.BundlePrice/100, props.BundlePrice%100)),
+		Allocations:  allocator.allocations(),
+	}, nil
+}
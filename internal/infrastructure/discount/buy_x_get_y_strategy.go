@@ -0,0 +1,82 @@
+// promotion-service/internal/infrastructure/discount/buy_x_get_y_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeBuyXGetY,
+		Name:          "买M赠N",
+		Strategy:      &BuyXGetYStrategy{},
+		NewProperties: func() Properties { return &BuyXGetYStrategyProperties{} },
+	})
+}
+
+// BuyXGetYStrategyProperties 定义了买M赠N策略所需的参数结构。
+// 例如 {"buy_quantity": 3, "get_quantity": 1, "categories": ["Drinks"]} 代表饮料每买3件，最便宜的1件免费。
+type BuyXGetYStrategyProperties struct {
+	ItemScope
+	BuyQuantity int32 `json:"buy_quantity" desc:"每买多少件（M）"`
+	GetQuantity int32 `json:"get_quantity" desc:"其中最便宜的多少件享受优惠（N）"`
+	Percentage  int32 `json:"percentage" desc:"优惠件的折扣率，例如50代表5折，0代表免费"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *BuyXGetYStrategyProperties) Validate() error {
+	if p.BuyQuantity <= 0 || p.GetQuantity <= 0 || p.GetQuantity > p.BuyQuantity {
+		return fmt.Errorf("invalid buy/get quantity: %d/%d", p.BuyQuantity, p.GetQuantity)
+	}
+	if p.Percentage < 0 || p.Percentage >= 100 {
+		return fmt.Errorf("invalid percentage value: %d", p.Percentage)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口，门槛为买满M件。
+func (p *BuyXGetYStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, quantity: p.BuyQuantity}
+}
+
+// BuyXGetYStrategy 实现了 domain.DiscountStrategy 接口，用于处理买M赠N（BOGO）优惠。
+// 它基于 Fact.Items 按件计算，而不是基于 Fact.TotalAmount。
+type BuyXGetYStrategy struct {
+	thresholdShortfall
+}
+
+func (s *BuyXGetYStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props BuyXGetYStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse buy x get y properties: %w", err)
+	}
+
+	runs := expandUnits(fact.Items, props.ItemScope)
+	groups := countUnits(runs) / int64(props.BuyQuantity)
+	if groups == 0 {
+		return &domain.DiscountApplication{Amount: 0}, nil // 件数不足，不优惠
+	}
+
+	// runs 已按单价降序排列，从末尾取最便宜的若干件
+	freeCount := groups * int64(props.GetQuantity)
+	allocator := newUnitAllocator(fact.Items)
+	for i := len(runs) - 1; i >= 0 && freeCount > 0; i-- {
+		count := min(runs[i].Count, freeCount)
+		allocator.add(runs[i], count, runs[i].Price*(100-int64(props.Percentage))/100)
+		freeCount -= count
+	}
+
+	description := fmt.Sprintf("每买%d件，最便宜的%d件免费", props.BuyQuantity, props.GetQuantity)
+	if props.Percentage > 0 {
+		description = fmt.Sprintf("每买%d件，最便宜的%d件享%d.%d折", props.BuyQuantity, props.GetQuantity, props.Percentage/10, props.Percentage%10)
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       allocator.total(),
+		StrategyName: "BuyXGetYStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Allocations:  allocator.allocations(),
+		Description:  description,
+	}, nil
+}
@@ -0,0 +1,82 @@
+// promotion-service/internal/infrastructure/discount/every_fixed_amount_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeEveryFixedAmount,
+		Name:          "每满减",
+		Strategy:      &EveryFixedAmountStrategy{},
+		NewProperties: func() Properties { return &EveryFixedAmountStrategyProperties{} },
+	})
+}
+
+// EveryFixedAmountStrategyProperties 定义了每满减策略所需的参数结构。
+type EveryFixedAmountStrategyProperties struct {
+	ItemScope
+	Step    int64 `json:"step" desc:"每满多少金额（单位：分）"`
+	Amount  int64 `json:"amount" desc:"每满一次减免的金额（单位：分）"`
+	Ceiling int64 `json:"ceiling" desc:"封顶金额（单位：分），可选，0代表不封顶"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *EveryFixedAmountStrategyProperties) Validate() error {
+	if p.Step <= 0 {
+		return fmt.Errorf("invalid step value: %d", p.Step)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("amount must be positive, got %d", p.Amount)
+	}
+	if p.Ceiling < 0 {
+		return fmt.Errorf("ceiling must not be negative, got %d", p.Ceiling)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口，门槛为满一个台阶。
+func (p *EveryFixedAmountStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Step}
+}
+
+// EveryFixedAmountStrategy 实现了 domain.DiscountStrategy 接口，用于处理每满减优惠。
+// 与 FixedAmountStrategy 只减一次不同，它按适用金额 / Step 的倍数累计减免。
+type EveryFixedAmountStrategy struct {
+	thresholdShortfall
+}
+
+func (s *EveryFixedAmountStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props EveryFixedAmountStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse every fixed amount properties: %w", err)
+	}
+
+	times := props.EligibleAmount(fact) / props.Step
+	if times <= 0 {
+		return &domain.DiscountApplication{Amount: 0}, nil // 未满一次，不优惠
+	}
+
+	// 计算优惠金额
+	discountAmount := times * props.Amount
+
+	// 检查是否超过封顶金额
+	if props.Ceiling > 0 && discountAmount > props.Ceiling {
+		discountAmount = props.Ceiling
+	}
+
+	description := fmt.Sprintf("每满%d.%02d元减%d.%02d元", props.Step/100, props.Step%100, props.Amount/100, props.Amount%100)
+	if props.Ceiling > 0 {
+		description += fmt.Sprintf("，最高减%d.%02d元", props.Ceiling/100, props.Ceiling%100)
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       discountAmount,
+		StrategyName: "EveryFixedAmountStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Allocations:  allocateProportionally(discountAmount, fact, props.ItemScope),
+		Description:  props.describe(description),
+	}, nil
+}
@@ -2,35 +2,65 @@
 package discount
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 )
 
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeFixedAmount,
+		Name:          "满减/立减",
+		Strategy:      &FixedAmountStrategy{},
+		NewProperties: func() Properties { return &FixedAmountStrategyProperties{} },
+	})
+}
+
 // FixedAmountStrategyProperties 定义了满减策略所需的参数结构。
 // 它将用于从 PromotionTemplate 的 DiscountProperties JSON字段中反序列化数据。
+// 配置了商品范围时，门槛基于范围内商品的小计判断，例如“电子产品满200减20”。
 type FixedAmountStrategyProperties struct {
-	Threshold int64 `json:"threshold"` // 满减门槛（单位：分）
-	Amount    int64 `json:"amount"`    // 优惠金额（单位：分）
+	ItemScope
+	Threshold int64 `json:"threshold" desc:"满减门槛（单位：分），0代表无门槛"`
+	Amount    int64 `json:"amount" desc:"优惠金额（单位：分）"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *FixedAmountStrategyProperties) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %d", p.Threshold)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("amount must be positive, got %d", p.Amount)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口。
+func (p *FixedAmountStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Threshold}
 }
 
 // FixedAmountStrategy 实现了 domain.DiscountStrategy 接口，用于处理满减/立减优惠。
-type FixedAmountStrategy struct{}
+type FixedAmountStrategy struct {
+	thresholdShortfall
+}
 
 func (s *FixedAmountStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
 	var props FixedAmountStrategyProperties
-	if err := json.Unmarshal([]byte(template.DiscountProperties), &props); err != nil {
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
 		return nil, fmt.Errorf("failed to parse fixed amount properties: %w", err)
 	}
 
 	// 检查是否达到满减门槛
-	if fact.TotalAmount < props.Threshold {
+	if props.EligibleAmount(fact) < props.Threshold {
 		return &domain.DiscountApplication{Amount: 0}, nil // 未达到门槛，不优惠
 	}
 
 	return &domain.DiscountApplication{
 		Amount:       props.Amount,
 		StrategyName: "FixedAmountStrategy",
-		Description:  fmt.Sprintf("满%d.%02d元减%d.%02d元", props.Threshold/100, props.Threshold%100, props.Amount/100, props.Amount%100),
+		Layer:        domain.DiscountLayerGoods,
+		Allocations:  allocateProportionally(props.Amount, fact, props.ItemScope),
+		Description:  props.describe(fmt.Sprintf("满%d.%02d元减%d.%02d元", props.Threshold/100, props.Threshold%100, props.Amount/100, props.Amount%100)),
 	}, nil
 }
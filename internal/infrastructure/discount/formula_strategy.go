@@ -0,0 +1,166 @@
+// promotion-service/internal/infrastructure/discount/formula_strategy.go
+package discount
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/rule"
+)
+
+func init() {
+	strategy := NewFormulaStrategy(nil)
+	Register(Registration{
+		Type:          domain.DiscountTypeFormula,
+		Name:          "公式",
+		Strategy:      strategy,
+		NewProperties: formulaProperties(strategy),
+	})
+}
+
+// formulaProperties 返回绑定到 strategy 的参数结构，校验时用同一个策略编译表达式，
+// 这样校验与计算使用同一个时区，编译结果也只缓存一份。
+func formulaProperties(strategy *FormulaStrategy) func() Properties {
+	return func() Properties { return &FormulaStrategyProperties{strategy: strategy} }
+}
+
+// FormulaStrategyProperties 定义了公式优惠策略所需的参数结构。
+// 例如 {"expression": "min(fact.TotalAmount / 10, 3000)", "description": "立减10%，最高30元"}。
+type FormulaStrategyProperties struct {
+	Expression  string `json:"expression" desc:"计算优惠金额（单位：分）的CEL表达式，必须返回int"`
+	Description string `json:"description" desc:"展示给用户的优惠描述，可选"`
+
+	strategy *FormulaStrategy // 用于编译表达式的策略，由注册表或策略自身设置
+}
+
+// Validate 实现了 Properties 接口，会用所属的策略实际编译表达式，以便在创建模板时发现语法和类型错误。
+func (p *FormulaStrategyProperties) Validate() error {
+	if p.strategy == nil {
+		return fmt.Errorf("formula properties are not bound to a strategy")
+	}
+	_, err := p.strategy.program(p.Expression)
+	return err
+}
+
+// FormulaStrategy 实现了 domain.DiscountStrategy 接口，用CEL表达式直接计算优惠金额。
+// 它让运营无需修改 StrategyFactory 就能上线一次性的优惠玩法。
+// 表达式与 rule.CelRuleEngine 使用同一套 fact 环境和促销函数（如 fact.Items.sumBy），编译结果按表达式缓存。
+type FormulaStrategy struct {
+	location     *time.Location // hourOfDay、dayOfWeek 等函数使用的时区，为 nil 时使用服务器本地时区
+	once         sync.Once
+	env          *cel.Env
+	envErr       error
+	programCache sync.Map
+}
+
+// NewFormulaStrategy 创建使用 loc 时区的公式策略，loc 应与规则引擎的 rule.WithTimezone 一致。
+func NewFormulaStrategy(loc *time.Location) *FormulaStrategy {
+	return &FormulaStrategy{location: loc}
+}
+
+func (s *FormulaStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	props := FormulaStrategyProperties{strategy: s}
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse formula properties: %w", err)
+	}
+
+	prg, err := s.program(props.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := prg.Eval(map[string]interface{}{
+		"fact": &fact,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("formula evaluation failed: %w", err)
+	}
+	discountAmount, ok := out.Value().(int64)
+	if !ok {
+		return nil, fmt.Errorf("formula result is not an int")
+	}
+
+	// 优惠金额不能为负，也不能超过订单总金额
+	if discountAmount <= 0 {
+		return &domain.DiscountApplication{Amount: 0}, nil
+	}
+	if discountAmount > fact.TotalAmount {
+		discountAmount = fact.TotalAmount
+	}
+
+	description := props.Description
+	if description == "" {
+		description = fmt.Sprintf("立减%d.%02d元", discountAmount/100, discountAmount%100)
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       discountAmount,
+		StrategyName: "FormulaStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Description:  description,
+		Allocations:  allocateProportionally(discountAmount, fact, ItemScope{}),
+	}, nil
+}
+
+// program 返回表达式对应的已编译程序，首次使用时编译并缓存。
+func (s *FormulaStrategy) program(expression string) (cel.Program, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("formula expression is required")
+	}
+	if cached, found := s.programCache.Load(expression); found {
+		return cached.(cel.Program), nil
+	}
+
+	s.once.Do(func() {
+		loc := s.location
+		if loc == nil {
+			loc = time.Local
+		}
+		s.env, s.envErr = rule.NewFactEnv(append(formulaFunctions(), rule.PromotionFunctions(loc))...)
+	})
+	if s.envErr != nil {
+		return nil, s.envErr
+	}
+
+	ast, issues := s.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("formula compilation failed: %w", issues.Err())
+	}
+	// 检查编译后的表达式输出类型是否为 int
+	if !ast.OutputType().IsExactType(cel.IntType) {
+		return nil, fmt.Errorf("formula must return an int value, but got %s", ast.OutputType())
+	}
+	prg, err := s.env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("program creation failed: %w", err)
+	}
+	s.programCache.Store(expression, prg)
+	return prg, nil
+}
+
+// formulaFunctions 声明公式中常用的 min/max 函数，方便表达封顶、保底等逻辑。
+func formulaFunctions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("min",
+			cel.Overload("min_int_int", []*cel.Type{cel.IntType, cel.IntType}, cel.IntType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					if lhs.(types.Int) < rhs.(types.Int) {
+						return lhs
+					}
+					return rhs
+				}))),
+		cel.Function("max",
+			cel.Overload("max_int_int", []*cel.Type{cel.IntType, cel.IntType}, cel.IntType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					if lhs.(types.Int) > rhs.(types.Int) {
+						return lhs
+					}
+					return rhs
+				}))),
+	}
+}
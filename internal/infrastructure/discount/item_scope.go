@@ -0,0 +1,164 @@
+// promotion-service/internal/infrastructure/discount/item_scope.go
+package discount
+
+import (
+	"sort"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// ItemScope 定义了策略适用的商品范围。
+// 包含条件中SKU、品类、品牌任一命中即视为适用，三者都为空时代表所有商品都适用；
+// 排除条件优先于包含条件，任一命中即不适用。
+type ItemScope struct {
+	SKUs              []string `json:"skus" desc:"适用的SKU列表"`
+	Categories        []string `json:"categories" desc:"适用的品类列表"`
+	Brands            []string `json:"brands" desc:"适用的品牌列表"`
+	ExcludeSKUs       []string `json:"exclude_skus" desc:"排除的SKU列表"`
+	ExcludeCategories []string `json:"exclude_categories" desc:"排除的品类列表"`
+	ExcludeBrands     []string `json:"exclude_brands" desc:"排除的品牌列表"`
+}
+
+// IsEmpty 判断是否未配置任何范围限制。
+func (s ItemScope) IsEmpty() bool {
+	return !s.hasIncludes() && !s.hasExcludes()
+}
+
+// Matches 判断一个购物车商品项是否落在适用范围内。
+func (s ItemScope) Matches(item domain.CartItem) bool {
+	if s.hasExcludes() &&
+		(contains(s.ExcludeSKUs, item.SKU) || contains(s.ExcludeCategories, item.Category) || contains(s.ExcludeBrands, item.Brand)) {
+		return false
+	}
+	if !s.hasIncludes() {
+		return true
+	}
+	return contains(s.SKUs, item.SKU) || contains(s.Categories, item.Category) || contains(s.Brands, item.Brand)
+}
+
+// EligibleAmount 计算适用范围内商品扣除已分摊优惠后的小计金额，门槛和折扣都应基于它计算。
+// 未配置范围限制时直接返回 Fact.TotalAmount，以兼容只传总金额的调用方。
+func (s ItemScope) EligibleAmount(fact domain.Fact) int64 {
+	if s.IsEmpty() {
+		return fact.TotalAmount
+	}
+	var subtotal int64
+	for _, item := range fact.Items {
+		if s.Matches(item) {
+			subtotal += item.Subtotal()
+		}
+	}
+	return subtotal
+}
+
+// describe 在优惠描述前加上范围前缀，便于用户理解优惠仅对部分商品生效。
+func (s ItemScope) describe(description string) string {
+	if s.IsEmpty() {
+		return description
+	}
+	return "指定商品" + description
+}
+
+func (s ItemScope) hasIncludes() bool {
+	return len(s.SKUs) > 0 || len(s.Categories) > 0 || len(s.Brands) > 0
+}
+
+func (s ItemScope) hasExcludes() bool {
+	return len(s.ExcludeSKUs) > 0 || len(s.ExcludeCategories) > 0 || len(s.ExcludeBrands) > 0
+}
+
+// unitRun 代表同一购物车行中单价相同的连续若干件商品。
+// 按件计算的策略以件为单位取商品，但件数来自请求，不能逐件展开，否则超大的件数会耗尽内存；
+// 因此以“单价 × 件数”的形式保存，按件数做算术切分。
+type unitRun struct {
+	LineIndex int    // 所在的购物车行（Fact.Items 的下标）
+	SKU       string // 商品SKU
+	Price     int64  // 每件商品扣除已分摊优惠后的应付金额（单位：分）
+	Count     int64  // 件数
+}
+
+// expandUnits 将适用范围内的商品按单价分段，并按单价从高到低排序。
+// 每件的单价取该行扣除已分摊优惠后的小计按件均分，除不尽的余数依次补到前几件上，
+// 这样与其它券叠加时不会对已经优惠掉的金额再次打折；因此每行最多拆成两段。
+// 单价相同时按行号升序，保证同一份购物车每次计算的结果都一致。
+func expandUnits(items []domain.CartItem, scope ItemScope) []unitRun {
+	runs := make([]unitRun, 0, len(items))
+	for i, item := range items {
+		if !scope.Matches(item) || item.Quantity <= 0 {
+			continue
+		}
+		quantity := int64(item.Quantity)
+		subtotal := item.Subtotal()
+		price, remainder := subtotal/quantity, subtotal%quantity
+		if remainder > 0 {
+			runs = append(runs, unitRun{LineIndex: i, SKU: item.SKU, Price: price + 1, Count: remainder})
+		}
+		runs = append(runs, unitRun{LineIndex: i, SKU: item.SKU, Price: price, Count: quantity - remainder})
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Price != runs[j].Price {
+			return runs[i].Price > runs[j].Price
+		}
+		return runs[i].LineIndex < runs[j].LineIndex
+	})
+	return runs
+}
+
+// countUnits 返回各段的总件数。
+func countUnits(runs []unitRun) int64 {
+	var count int64
+	for _, run := range runs {
+		count += run.Count
+	}
+	return count
+}
+
+// unitCursor 按排好序的顺序从各段中依次取出商品。
+type unitCursor struct {
+	runs   []unitRun
+	index  int   // 当前所在的段
+	offset int64 // 当前段中已取出的件数
+}
+
+// remainingInRun 返回当前段中还未取出的件数，已取完所有段时返回0。
+func (c *unitCursor) remainingInRun() int64 {
+	if c.index >= len(c.runs) {
+		return 0
+	}
+	return c.runs[c.index].Count - c.offset
+}
+
+// take 取出接下来的 n 件商品，按段返回；剩余件数不足 n 件时不移动位置并返回 false。
+func (c *unitCursor) take(n int64) ([]unitRun, bool) {
+	available := -c.offset
+	for i := c.index; i < len(c.runs) && available < n; i++ {
+		available += c.runs[i].Count
+	}
+	if available < n {
+		return nil, false
+	}
+
+	taken := make([]unitRun, 0, 2)
+	for n > 0 {
+		run := c.runs[c.index]
+		count := min(run.Count-c.offset, n)
+		run.Count = count
+		taken = append(taken, run)
+		n -= count
+		c.offset += count
+		if c.offset == c.runs[c.index].Count {
+			c.index, c.offset = c.index+1, 0
+		}
+	}
+	return taken, true
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// promotion-service/internal/infrastructure/discount/nth_item_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"strings"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeNthItem,
+		Name:          "第N件优惠",
+		Strategy:      &NthItemStrategy{},
+		NewProperties: func() Properties { return &NthItemStrategyProperties{} },
+	})
+}
+
+// NthItemStrategyProperties 定义了第N件优惠策略所需的参数结构。
+// PositionPercentages 的第 i 个元素是第 i+1 件的折扣率，其长度即为一个循环的件数。
+// 例如 [100, 50] 代表“第二件半价”，[100, 100, 0] 代表“第三件免费”。
+type NthItemStrategyProperties struct {
+	ItemScope
+	PositionPercentages []int32 `json:"position_percentages" desc:"每个位置的折扣率，100代表原价，0代表免费"`
+	PerSKU              bool    `json:"per_sku" desc:"是否只在同一SKU内计件（跨多行的同一SKU会合并计算）"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *NthItemStrategyProperties) Validate() error {
+	if len(p.PositionPercentages) < 2 {
+		return fmt.Errorf("nth item properties must define at least two positions")
+	}
+	for i, percentage := range p.PositionPercentages {
+		if percentage < 0 || percentage > 100 {
+			return fmt.Errorf("position_percentages[%d] is invalid: %d", i, percentage)
+		}
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口，门槛为凑满一个循环的件数。
+func (p *NthItemStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, quantity: int32(len(p.PositionPercentages)), perSKU: p.PerSKU}
+}
+
+// NthItemStrategy 实现了 domain.DiscountStrategy 接口，用于处理“第二件半价”、“第三件免费”等优惠。
+// 它将适用商品按件展开、按单价从高到低排序，再按循环位置逐件打折，因此优惠总是落在较便宜的商品上。
+type NthItemStrategy struct {
+	thresholdShortfall
+}
+
+func (s *NthItemStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props NthItemStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse nth item properties: %w", err)
+	}
+
+	runs := expandUnits(fact.Items, props.ItemScope)
+
+	// 按计件分组：默认所有适用商品混合计件，PerSKU 时每个SKU单独计件
+	var groups [][]unitRun
+	if props.PerSKU {
+		indexBySKU := make(map[string]int)
+		for _, run := range runs {
+			idx, ok := indexBySKU[run.SKU]
+			if !ok {
+				idx = len(groups)
+				indexBySKU[run.SKU] = idx
+				groups = append(groups, nil)
+			}
+			groups[idx] = append(groups[idx], run)
+		}
+	} else {
+		groups = [][]unitRun{runs}
+	}
+
+	cycle := int64(len(props.PositionPercentages))
+	allocator := newUnitAllocator(fact.Items)
+	for _, group := range groups {
+		// 只有凑满完整的循环才享受优惠
+		complete := countUnits(group) / cycle * cycle
+		var position int64
+		for _, run := range group {
+			count := min(run.Count, complete-position)
+			if count <= 0 {
+				break
+			}
+			// 段内第 position 到 position+count-1 件依次落在循环的各个位置上
+			for i, percentage := range props.PositionPercentages {
+				n := unitsAtPosition(position+count, cycle, int64(i)) - unitsAtPosition(position, cycle, int64(i))
+				allocator.add(run, n, run.Price*(100-int64(percentage))/100)
+			}
+			position += count
+		}
+	}
+
+	discountAmount := allocator.total()
+	if discountAmount == 0 {
+		return &domain.DiscountApplication{Amount: 0}, nil // 件数不足，不优惠
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       discountAmount,
+		StrategyName: "NthItemStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Allocations:  allocator.allocations(),
+		Description:  describeNthItem(props.PositionPercentages),
+	}, nil
+}
+
+// describeNthItem 生成诸如“第2件5.0折”、“第3件免费”的描述。
+func describeNthItem(percentages []int32) string {
+	parts := make([]string, 0, len(percentages))
+	for i, p := range percentages {
+		switch {
+		case p == 100:
+			continue
+		case p == 0:
+			parts = append(parts, fmt.Sprintf("第%d件免费", i+1))
+		default:
+			parts = append(parts, fmt.Sprintf("第%d件%d.%d折", i+1, p/10, p%10))
+		}
+	}
+	return strings.Join(parts, "，")
+}
+
+// unitsAtPosition 返回前 n 件商品中落在循环第 position 个位置（从0开始）上的件数。
+func unitsAtPosition(n, cycle, position int64) int64 {
+	count := n / cycle
+	if n%cycle > position {
+		count++
+	}
+	return count
+}
@@ -2,15 +2,36 @@
 package discount
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 )
 
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypePercentage,
+		Name:          "折扣",
+		Strategy:      &PercentageStrategy{},
+		NewProperties: func() Properties { return &PercentageStrategyProperties{} },
+	})
+}
+
 // PercentageStrategyProperties 定义了折扣策略所需的参数结构。
+// 配置了商品范围时，只对范围内的商品打折，例如“电子产品9折”不会对其它品类打折。
 type PercentageStrategyProperties struct {
-	Percentage int32 `json:"percentage"` // 折扣率，例如88代表8.8折
-	Ceiling    int64 `json:"ceiling"`    // 封顶金额（单位：分），可选，0代表不封顶
+	ItemScope
+	Percentage int32 `json:"percentage" desc:"折扣率，例如88代表8.8折"`
+	Ceiling    int64 `json:"ceiling" desc:"封顶金额（单位：分），可选，0代表不封顶"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *PercentageStrategyProperties) Validate() error {
+	if p.Percentage <= 0 || p.Percentage >= 100 {
+		return fmt.Errorf("invalid percentage value: %d", p.Percentage)
+	}
+	if p.Ceiling < 0 {
+		return fmt.Errorf("ceiling must not be negative, got %d", p.Ceiling)
+	}
+	return nil
 }
 
 // PercentageStrategy 实现了 domain.DiscountStrategy 接口，用于处理折扣优惠。
@@ -18,16 +39,12 @@ type PercentageStrategy struct{}
 
 func (s *PercentageStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
 	var props PercentageStrategyProperties
-	if err := json.Unmarshal([]byte(template.DiscountProperties), &props); err != nil {
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
 		return nil, fmt.Errorf("failed to parse percentage properties: %w", err)
 	}
 
-	if props.Percentage <= 0 || props.Percentage >= 100 {
-		return nil, fmt.Errorf("invalid percentage value: %d", props.Percentage)
-	}
-
 	// 计算优惠金额
-	discountAmount := fact.TotalAmount * (100 - int64(props.Percentage)) / 100
+	discountAmount := props.EligibleAmount(fact) * (100 - int64(props.Percentage)) / 100
 
 	// 检查是否超过封顶金额
 	if props.Ceiling > 0 && discountAmount > props.Ceiling {
@@ -37,6 +54,8 @@ func (s *PercentageStrategy) Calculate(fact domain.Fact, template *domain.Promot
 	return &domain.DiscountApplication{
 		Amount:       discountAmount,
 		StrategyName: "PercentageStrategy",
-		Description:  fmt.Sprintf("享受%d.%d折优惠", props.Percentage/10, props.Percentage%10),
+		Layer:        domain.DiscountLayerGoods,
+		Allocations:  allocateProportionally(discountAmount, fact, props.ItemScope),
+		Description:  props.describe(fmt.Sprintf("享受%d.%d折优惠", props.Percentage/10, props.Percentage%10)),
 	}, nil
 }
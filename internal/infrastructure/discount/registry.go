@@ -0,0 +1,139 @@
+// promotion-service/internal/infrastructure/discount/registry.go
+package discount
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// Properties 是每种策略的参数结构需要实现的接口。
+// 参数结构的字段通过 json 标签声明 DiscountProperties 中的键名，通过 desc 标签声明字段说明，
+// 注册表据此向管理后台输出参数结构描述。
+type Properties interface {
+	// Validate 校验参数取值是否合法，错误信息应指明具体的字段
+	Validate() error
+}
+
+// Registration 描述一种优惠类型的注册信息。
+type Registration struct {
+	Type          domain.DiscountType     // 优惠类型
+	Name          string                  // 展示名称, e.g. "阶梯满减"
+	Strategy      domain.DiscountStrategy // 策略实例，会被并发使用
+	NewProperties func() Properties       // 返回一个空的参数结构指针
+}
+
+// registrations 保存所有已注册的优惠类型，由各策略文件在 init 中填充。
+var registrations = make(map[domain.DiscountType]Registration)
+
+// Register 注册一种优惠类型。新增优惠类型时只需在策略文件的 init 中调用它，无需修改工厂。
+// 重复注册同一类型属于编程错误，会直接 panic。
+func Register(r Registration) {
+	if r.Type == "" || r.Strategy == nil || r.NewProperties == nil {
+		panic(fmt.Sprintf("discount: incomplete registration for type %q", r.Type))
+	}
+	if _, exists := registrations[r.Type]; exists {
+		panic(fmt.Sprintf("discount: type %q registered twice", r.Type))
+	}
+	registrations[r.Type] = r
+}
+
+// StrategyDescriptor 是对外暴露的优惠类型描述，管理后台据此渲染模板表单。
+type StrategyDescriptor struct {
+	Type       domain.DiscountType
+	Name       string
+	Layer      domain.DiscountLayer
+	Properties []PropertySchema
+}
+
+// PropertySchema 描述参数结构中的一个字段。
+type PropertySchema struct {
+	Name        string           // DiscountProperties 中的键名
+	Type        string           // integer / string / boolean / array / object
+	Description string           // 字段说明
+	Items       *PropertySchema  // Type 为 array 时，数组元素的结构
+	Properties  []PropertySchema // Type 为 object 时，对象的字段
+}
+
+// parseProperties 解析并校验模板上的策略参数，供策略在计算时使用。
+// 为兼容历史模板，这里会忽略未声明的字段。
+func parseProperties(raw string, props Properties) error {
+	if err := json.Unmarshal([]byte(raw), props); err != nil {
+		return err
+	}
+	return props.Validate()
+}
+
+// parsePropertiesStrict 与 parseProperties 相同，但遇到未声明的字段会报错，
+// 用于创建模板时尽早发现键名拼写错误。
+func parsePropertiesStrict(raw string, props Properties) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(props); err != nil {
+		return err
+	}
+	return props.Validate()
+}
+
+// schemaOf 通过反射生成参数结构的字段描述，匿名嵌入的结构（如 ItemScope）会被展开。
+func schemaOf(t reflect.Type) []PropertySchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	schemas := make([]PropertySchema, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			schemas = append(schemas, schemaOf(field.Type)...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		schema := schemaOfType(field.Type)
+		schema.Name = name
+		schema.Description = field.Tag.Get("desc")
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
+
+func schemaOfType(t reflect.Type) PropertySchema {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return PropertySchema{Type: "integer"}
+	case reflect.Bool:
+		return PropertySchema{Type: "boolean"}
+	case reflect.Slice, reflect.Array:
+		items := schemaOfType(t.Elem())
+		return PropertySchema{Type: "array", Items: &items}
+	case reflect.Struct:
+		return PropertySchema{Type: "object", Properties: schemaOf(t)}
+	default:
+		return PropertySchema{Type: "string"}
+	}
+}
+
+// describeRegistrations 按优惠类型排序输出所有注册信息的描述。
+func describeRegistrations(regs map[domain.DiscountType]Registration) []StrategyDescriptor {
+	descriptors := make([]StrategyDescriptor, 0, len(regs))
+	for _, r := range regs {
+		descriptors = append(descriptors, StrategyDescriptor{
+			Type:       r.Type,
+			Name:       r.Name,
+			Layer:      r.Type.Layer(),
+			Properties: schemaOf(reflect.TypeOf(r.NewProperties())),
+		})
+	}
+	sort.Slice(descriptors, func(i, j int) bool {
+		return descriptors[i].Type < descriptors[j].Type
+	})
+	return descriptors
+}
@@ -0,0 +1,206 @@
+// promotion-service/internal/infrastructure/discount/reward_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypePoints,
+		Name:          "赠送积分",
+		Strategy:      &PointsStrategy{},
+		NewProperties: func() Properties { return &PointsStrategyProperties{} },
+	})
+	Register(Registration{
+		Type:          domain.DiscountTypeCashback,
+		Name:          "下单返现",
+		Strategy:      &CashbackStrategy{},
+		NewProperties: func() Properties { return &CashbackStrategyProperties{} },
+	})
+	Register(Registration{
+		Type:          domain.DiscountTypeGift,
+		Name:          "赠品",
+		Strategy:      &GiftStrategy{},
+		NewProperties: func() Properties { return &GiftStrategyProperties{} },
+	})
+}
+
+// 权益类策略不减少应付金额，返回的 DiscountApplication.Amount 恒为0，
+// 权益本身放在 Reward 中，并按模板配置折算出等价金额供择优比较。
+
+// PointsStrategyProperties 定义了赠送积分策略所需的参数结构。
+type PointsStrategyProperties struct {
+	ItemScope
+	Threshold  int64 `json:"threshold" desc:"赠送门槛（单位：分）"`
+	Points     int64 `json:"points" desc:"赠送的积分数"`
+	PointValue int64 `json:"point_value" desc:"每积分折算的金额（单位：分），用于择优比较"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *PointsStrategyProperties) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %d", p.Threshold)
+	}
+	if p.Points <= 0 {
+		return fmt.Errorf("invalid points value: %d", p.Points)
+	}
+	if p.PointValue < 0 {
+		return fmt.Errorf("point_value must not be negative, got %d", p.PointValue)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口。
+func (p *PointsStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Threshold}
+}
+
+// PointsStrategy 实现了 domain.DiscountStrategy 接口，用于处理满额赠送积分。
+type PointsStrategy struct {
+	thresholdShortfall
+}
+
+func (s *PointsStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props PointsStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse points properties: %w", err)
+	}
+	if props.EligibleAmount(fact) < props.Threshold {
+		return &domain.DiscountApplication{Amount: 0}, nil // 未达到门槛，不赠送
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       0,
+		StrategyName: "PointsStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Description:  props.describe(fmt.Sprintf("满%d.%02d元赠送%d积分", props.Threshold/100, props.Threshold%100, props.Points)),
+		Reward: &domain.Reward{
+			Type:          domain.RewardTypePoints,
+			Points:        props.Points,
+			MonetaryValue: props.Points * props.PointValue,
+		},
+	}, nil
+}
+
+// CashbackStrategyProperties 定义了下单返现策略所需的参数结构。
+type CashbackStrategyProperties struct {
+	ItemScope
+	Threshold int64 `json:"threshold" desc:"返现门槛（单位：分）"`
+	Amount    int64 `json:"amount" desc:"返现金额（单位：分）"`
+	ValueRate int64 `json:"value_rate" desc:"择优时的折算比例（百分比），0代表按面值折算"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *CashbackStrategyProperties) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %d", p.Threshold)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("invalid cashback amount: %d", p.Amount)
+	}
+	if p.ValueRate < 0 || p.ValueRate > 100 {
+		return fmt.Errorf("invalid value rate: %d", p.ValueRate)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口。
+func (p *CashbackStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Threshold}
+}
+
+// CashbackStrategy 实现了 domain.DiscountStrategy 接口，用于处理下单返现。
+// 返现在订单完成后发放，对用户的吸引力通常低于立减，因此允许按比例折算。
+type CashbackStrategy struct {
+	thresholdShortfall
+}
+
+func (s *CashbackStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props CashbackStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse cashback properties: %w", err)
+	}
+	if props.EligibleAmount(fact) < props.Threshold {
+		return &domain.DiscountApplication{Amount: 0}, nil // 未达到门槛，不返现
+	}
+
+	valueRate := props.ValueRate
+	if valueRate == 0 {
+		valueRate = 100
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       0,
+		StrategyName: "CashbackStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Description:  props.describe(fmt.Sprintf("满%d.%02d元返现%d.%02d元", props.Threshold/100, props.Threshold%100, props.Amount/100, props.Amount%100)),
+		Reward: &domain.Reward{
+			Type:          domain.RewardTypeCashback,
+			Cashback:      props.Amount,
+			MonetaryValue: props.Amount * valueRate / 100,
+		},
+	}, nil
+}
+
+// GiftStrategyProperties 定义了赠品策略所需的参数结构。
+type GiftStrategyProperties struct {
+	ItemScope
+	Threshold    int64  `json:"threshold" desc:"赠送门槛（单位：分）"`
+	GiftSKU      string `json:"gift_sku" desc:"赠品SKU"`
+	GiftQuantity int32  `json:"gift_quantity" desc:"赠品数量，0代表1件"`
+	GiftValue    int64  `json:"gift_value" desc:"单件赠品折算的金额（单位：分），用于择优比较"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *GiftStrategyProperties) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %d", p.Threshold)
+	}
+	if p.GiftSKU == "" {
+		return fmt.Errorf("gift sku is required")
+	}
+	if p.GiftQuantity < 0 || p.GiftValue < 0 {
+		return fmt.Errorf("gift_quantity and gift_value must not be negative")
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口。
+func (p *GiftStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Threshold}
+}
+
+// GiftStrategy 实现了 domain.DiscountStrategy 接口，用于处理满额赠品。
+type GiftStrategy struct {
+	thresholdShortfall
+}
+
+func (s *GiftStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props GiftStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse gift properties: %w", err)
+	}
+	if props.EligibleAmount(fact) < props.Threshold {
+		return &domain.DiscountApplication{Amount: 0}, nil // 未达到门槛，不赠送
+	}
+
+	quantity := props.GiftQuantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       0,
+		StrategyName: "GiftStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Description:  props.describe(fmt.Sprintf("满%d.%02d元赠%s x%d", props.Threshold/100, props.Threshold%100, props.GiftSKU, quantity)),
+		Reward: &domain.Reward{
+			Type:          domain.RewardTypeGift,
+			GiftSKU:       props.GiftSKU,
+			GiftQuantity:  quantity,
+			MonetaryValue: props.GiftValue * int64(quantity),
+		},
+	}, nil
+}
@@ -0,0 +1,139 @@
+// promotion-service/internal/infrastructure/discount/shipping_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeFreeShipping,
+		Name:          "包邮",
+		Strategy:      &FreeShippingStrategy{},
+		NewProperties: func() Properties { return &FreeShippingStrategyProperties{} },
+	})
+	Register(Registration{
+		Type:          domain.DiscountTypeShippingDiscount,
+		Name:          "运费减免",
+		Strategy:      &ShippingDiscountStrategy{},
+		NewProperties: func() Properties { return &ShippingDiscountStrategyProperties{} },
+	})
+}
+
+// RegionScope 定义了运费优惠适用的收货地区。
+// Regions 为空代表所有地区都适用；ExcludeRegions 优先于 Regions。
+type RegionScope struct {
+	Regions        []string `json:"regions" desc:"适用的收货地区"`
+	ExcludeRegions []string `json:"exclude_regions" desc:"排除的收货地区，如偏远地区"`
+}
+
+// Matches 判断收货地区是否适用。
+func (s RegionScope) Matches(region string) bool {
+	if contains(s.ExcludeRegions, region) {
+		return false
+	}
+	return len(s.Regions) == 0 || contains(s.Regions, region)
+}
+
+// FreeShippingStrategyProperties 定义了包邮策略所需的参数结构。
+type FreeShippingStrategyProperties struct {
+	ItemScope
+	RegionScope
+	Threshold int64 `json:"threshold" desc:"包邮门槛，基于商品金额（单位：分）"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *FreeShippingStrategyProperties) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %d", p.Threshold)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口。
+func (p *FreeShippingStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Threshold}
+}
+
+// FreeShippingStrategy 实现了 domain.DiscountStrategy 接口，用于处理包邮优惠。
+// 它只减免运费，不影响商品金额。
+type FreeShippingStrategy struct {
+	thresholdShortfall
+}
+
+func (s *FreeShippingStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props FreeShippingStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse free shipping properties: %w", err)
+	}
+
+	if fact.Shipping.Fee <= 0 || !props.RegionScope.Matches(fact.Shipping.Region) || props.EligibleAmount(fact) < props.Threshold {
+		return &domain.DiscountApplication{Amount: 0, Layer: domain.DiscountLayerShipping}, nil // 无运费、地区不适用或未达门槛
+	}
+
+	description := "包邮"
+	if props.Threshold > 0 {
+		description = fmt.Sprintf("满%d.%02d元包邮", props.Threshold/100, props.Threshold%100)
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       fact.Shipping.Fee,
+		StrategyName: "FreeShippingStrategy",
+		Layer:        domain.DiscountLayerShipping,
+		Description:  props.ItemScope.describe(description),
+	}, nil
+}
+
+// ShippingDiscountStrategyProperties 定义了运费减免策略所需的参数结构。
+type ShippingDiscountStrategyProperties struct {
+	ItemScope
+	RegionScope
+	Threshold int64 `json:"threshold" desc:"减免门槛，基于商品金额（单位：分）"`
+	Amount    int64 `json:"amount" desc:"运费最多减免的金额（单位：分）"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *ShippingDiscountStrategyProperties) Validate() error {
+	if p.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %d", p.Threshold)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("invalid shipping discount amount: %d", p.Amount)
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口。
+func (p *ShippingDiscountStrategyProperties) threshold() threshold {
+	return threshold{scope: p.ItemScope, amount: p.Threshold}
+}
+
+// ShippingDiscountStrategy 实现了 domain.DiscountStrategy 接口，用于处理运费减免优惠。
+// 减免金额不会超过实际运费。
+type ShippingDiscountStrategy struct {
+	thresholdShortfall
+}
+
+func (s *ShippingDiscountStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props ShippingDiscountStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse shipping discount properties: %w", err)
+	}
+
+	if fact.Shipping.Fee <= 0 || !props.RegionScope.Matches(fact.Shipping.Region) || props.EligibleAmount(fact) < props.Threshold {
+		return &domain.DiscountApplication{Amount: 0, Layer: domain.DiscountLayerShipping}, nil // 无运费、地区不适用或未达门槛
+	}
+
+	discountAmount := props.Amount
+	if discountAmount > fact.Shipping.Fee {
+		discountAmount = fact.Shipping.Fee
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       discountAmount,
+		StrategyName: "ShippingDiscountStrategy",
+		Layer:        domain.DiscountLayerShipping,
+		Description:  props.ItemScope.describe(fmt.Sprintf("满%d.%02d元运费减%d.%02d元", props.Threshold/100, props.Threshold%100, props.Amount/100, props.Amount%100)),
+	}, nil
+}
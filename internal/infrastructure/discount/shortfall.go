@@ -0,0 +1,78 @@
+// promotion-service/internal/infrastructure/discount/shortfall.go
+package discount
+
+import (
+	"fmt"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// threshold 描述策略参数中的最低门槛，金额门槛与件数门槛二选一。
+type threshold struct {
+	scope    ItemScope // 参与门槛计算的商品范围
+	amount   int64     // 适用商品金额需要达到的值（单位：分）
+	quantity int32     // 适用商品件数需要达到的值，大于0时按件数计算
+	perSKU   bool      // 件数是否只在同一SKU内累计
+}
+
+// thresholdProperties 由带门槛的策略参数实现。参数只需声明自己的门槛，差额统一由 threshold 计算。
+type thresholdProperties interface {
+	Properties
+	threshold() threshold
+}
+
+// thresholdShortfall 嵌入到带门槛的策略中，为其实现 domain.ShortfallStrategy：
+// 按模板的优惠类型从注册表新建参数结构并解析，再根据参数声明的门槛计算差额。
+type thresholdShortfall struct{}
+
+// Shortfall 实现了 domain.ShortfallStrategy 接口。
+func (thresholdShortfall) Shortfall(fact domain.Fact, template *domain.PromotionTemplate) (domain.Shortfall, error) {
+	r, ok := registrations[template.DiscountType]
+	if !ok {
+		return domain.Shortfall{}, fmt.Errorf("unsupported discount type: %s", template.DiscountType)
+	}
+	props, ok := r.NewProperties().(thresholdProperties)
+	if !ok {
+		return domain.Shortfall{}, nil
+	}
+	if err := parseProperties(template.DiscountProperties, props); err != nil {
+		return domain.Shortfall{}, err
+	}
+	return props.threshold().shortfall(fact), nil
+}
+
+// shortfall 返回距离门槛还差的金额或件数，已达到时返回零值。
+func (t threshold) shortfall(fact domain.Fact) domain.Shortfall {
+	if t.quantity > 0 {
+		return domain.Shortfall{Quantity: t.missingUnits(fact)}
+	}
+	if eligible := t.scope.EligibleAmount(fact); eligible < t.amount {
+		return domain.Shortfall{Amount: t.amount - eligible}
+	}
+	return domain.Shortfall{}
+}
+
+// missingUnits 返回还差的件数。按SKU计件时以最接近凑满的SKU为准，购物车中没有适用商品时需要买满整个门槛。
+func (t threshold) missingUnits(fact domain.Fact) int32 {
+	runs := expandUnits(fact.Items, t.scope)
+	quantity := int64(t.quantity)
+	if !t.perSKU {
+		if count := countUnits(runs); count < quantity {
+			return int32(quantity - count)
+		}
+		return 0
+	}
+
+	countBySKU := make(map[string]int64)
+	for _, run := range runs {
+		countBySKU[run.SKU] += run.Count
+	}
+	missing := quantity
+	for _, count := range countBySKU {
+		if count >= quantity {
+			return 0
+		}
+		missing = min(missing, quantity-count)
+	}
+	return int32(missing)
+}
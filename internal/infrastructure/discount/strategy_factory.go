@@ -3,28 +3,63 @@ package discount
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 )
 
 // StrategyFactory 负责创建和提供具体的优惠计算策略实例。
 // 这是工厂模式的直接应用，它将策略的创建逻辑与使用逻辑解耦。
-type StrategyFactory struct{}
+// 支持的优惠类型来自注册表，新增类型只需在策略文件中调用 Register。
+type StrategyFactory struct {
+	registrations map[domain.DiscountType]Registration
+}
 
-func NewStrategyFactory() *StrategyFactory {
-	return &StrategyFactory{}
+// FactoryOption 用于定制 StrategyFactory。
+type FactoryOption func(*StrategyFactory)
+
+// WithTimezone 设置公式策略中 hourOfDay、dayOfWeek 等函数使用的时区，应与规则引擎的 rule.WithTimezone 一致。
+// 默认使用服务器本地时区。
+func WithTimezone(loc *time.Location) FactoryOption {
+	return func(f *StrategyFactory) {
+		strategy := NewFormulaStrategy(loc)
+		r := f.registrations[domain.DiscountTypeFormula]
+		r.Strategy, r.NewProperties = strategy, formulaProperties(strategy)
+		f.registrations[domain.DiscountTypeFormula] = r
+	}
+}
+
+func NewStrategyFactory(opts ...FactoryOption) *StrategyFactory {
+	regs := make(map[domain.DiscountType]Registration, len(registrations))
+	for t, r := range registrations {
+		regs[t] = r
+	}
+	f := &StrategyFactory{registrations: regs}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
 // CreateStrategy 根据传入的优惠类型，返回一个具体的策略实现。
 func (f *StrategyFactory) CreateStrategy(discountType domain.DiscountType) (domain.DiscountStrategy, error) {
-	switch discountType {
-	case domain.DiscountTypeFixedAmount:
-		return &FixedAmountStrategy{}, nil
-	case domain.DiscountTypePercentage:
-		return &PercentageStrategy{}, nil
-	// 当需要添加新的优惠类型时，只需在这里增加一个新的case分支。
-	// case domain.DiscountTypeBuyOneGetOne:
-	// 	return &BuyOneGetOneStrategy{}, nil
-	default:
+	r, ok := f.registrations[discountType]
+	if !ok {
 		return nil, fmt.Errorf("unsupported discount type: %s", discountType)
 	}
+	return r.Strategy, nil
+}
+
+// ValidateProperties 按优惠类型对应的参数结构严格解析并校验 DiscountProperties。
+func (f *StrategyFactory) ValidateProperties(discountType domain.DiscountType, properties string) error {
+	r, ok := f.registrations[discountType]
+	if !ok {
+		return fmt.Errorf("unsupported discount type: %s", discountType)
+	}
+	return parsePropertiesStrict(properties, r.NewProperties())
+}
+
+// SupportedTypes 返回所有支持的优惠类型及其参数结构描述。
+func (f *StrategyFactory) SupportedTypes() []StrategyDescriptor {
+	return describeRegistrations(f.registrations)
 }
@@ -0,0 +1,606 @@
+// internal/infrastructure/discount/strategy_test.go
+package discount
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestTieredFixedAmountStrategy_PicksHighestReachedTier(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeTieredFixedAmount,
+		DiscountProperties: `{"tiers": [{"threshold": 20000, "amount": 3000}, {"threshold": 10000, "amount": 1000}, {"threshold": 50000, "amount": 10000}]}`,
+	}
+
+	cases := []struct {
+		name   string
+		total  int64
+		expect int64
+	}{
+		{"below lowest tier", 9999, 0},
+		{"exactly first tier", 10000, 1000},
+		{"between tiers", 45000, 3000},
+		{"top tier", 80000, 10000},
+	}
+
+	strategy := &TieredFixedAmountStrategy{}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			offer, err := strategy.Calculate(domain.Fact{TotalAmount: tc.total}, template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if offer.Amount != tc.expect {
+				t.Errorf("expected discount %d; got %d", tc.expect, offer.Amount)
+			}
+		})
+	}
+}
+
+func TestEveryFixedAmountStrategy_RepeatsUpToCeiling(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeEveryFixedAmount,
+		DiscountProperties: `{"step": 10000, "amount": 1000, "ceiling": 5000}`,
+	}
+
+	cases := []struct {
+		name   string
+		total  int64
+		expect int64
+	}{
+		{"below one step", 9999, 0},
+		{"two steps", 25000, 2000},
+		{"capped by ceiling", 99900, 5000},
+	}
+
+	strategy := &EveryFixedAmountStrategy{}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			offer, err := strategy.Calculate(domain.Fact{TotalAmount: tc.total}, template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if offer.Amount != tc.expect {
+				t.Errorf("expected discount %d; got %d", tc.expect, offer.Amount)
+			}
+		})
+	}
+}
+
+func TestBuyXGetYStrategy_CheapestQualifyingUnitsAreFree(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeBuyXGetY,
+		DiscountProperties: `{"buy_quantity": 3, "get_quantity": 1, "categories": ["Drinks"]}`,
+	}
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "COLA", Price: 300, Quantity: 4, Category: "Drinks"},
+			{SKU: "JUICE", Price: 800, Quantity: 2, Category: "Drinks"},
+			{SKU: "CHIPS", Price: 100, Quantity: 5, Category: "Snacks"},
+		},
+	}
+
+	offer, err := (&BuyXGetYStrategy{}).Calculate(fact, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 6件饮料可凑成2组，最便宜的2件可乐免费；零食不在适用范围内
+	if offer.Amount != 600 {
+		t.Errorf("expected discount 600; got %d", offer.Amount)
+	}
+}
+
+func TestUnitStrategies_PriceUnitsFromRemainingSubtotal(t *testing.T) {
+	// 两行各已分摊了其它券的优惠：COLA 剩余 1000/4 件，JUICE 已被优惠完
+	items := []domain.CartItem{
+		{SKU: "COLA", Price: 300, Quantity: 4, Category: "Drinks", Discount: 201},
+		{SKU: "JUICE", Price: 800, Quantity: 2, Category: "Drinks", Discount: 1600},
+	}
+
+	cases := []struct {
+		name        string
+		strategy    domain.DiscountStrategy
+		template    *domain.PromotionTemplate
+		expect      int64
+		allocations []domain.LineAllocation
+	}{
+		{
+			// 按件均分 999：250、250、250、249，最便宜的一件免费
+			name:        "buy x get y",
+			strategy:    &BuyXGetYStrategy{},
+			template:    &domain.PromotionTemplate{DiscountProperties: `{"buy_quantity": 3, "get_quantity": 1, "skus": ["COLA"]}`},
+			expect:      249,
+			allocations: []domain.LineAllocation{{LineIndex: 0, SKU: "COLA", Amount: 249}},
+		},
+		{
+			// JUICE 的两件单价为0，即使算作第二件也不会再优惠
+			name:        "nth item",
+			strategy:    &NthItemStrategy{},
+			template:    &domain.PromotionTemplate{DiscountProperties: `{"position_percentages": [100, 0]}`},
+			expect:      499,
+			allocations: []domain.LineAllocation{{LineIndex: 0, SKU: "COLA", Amount: 499}},
+		},
+		{
+			// 四件 COLA 成组，应付 999，一口价 500
+			name:        "bundle price",
+			strategy:    &BundlePriceStrategy{},
+			template:    &domain.PromotionTemplate{DiscountProperties: `{"bundle_size": 4, "bundle_price": 500}`},
+			expect:      499,
+			allocations: []domain.LineAllocation{{LineIndex: 0, SKU: "COLA", Amount: 499}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			offer, err := tc.strategy.Calculate(domain.Fact{Items: items}, tc.template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if offer.Amount != tc.expect {
+				t.Errorf("expected discount %d; got %d", tc.expect, offer.Amount)
+			}
+			if len(offer.Allocations) != len(tc.allocations) {
+				t.Fatalf("expected allocations %+v; got %+v", tc.allocations, offer.Allocations)
+			}
+			for i, a := range offer.Allocations {
+				if a != tc.allocations[i] {
+					t.Errorf("expected allocation %+v; got %+v", tc.allocations[i], a)
+				}
+				if a.Amount > items[a.LineIndex].Subtotal() {
+					t.Errorf("allocation %+v exceeds the payable amount of its line", a)
+				}
+			}
+		})
+	}
+}
+
+func TestUnitStrategies_HandleHugeQuantitiesWithoutExpandingUnits(t *testing.T) {
+	// 件数来自请求，按件展开会分配20亿个元素；按单价分段后只需常数内存
+	items := []domain.CartItem{
+		{SKU: "PEN", Price: 100, Quantity: 2_000_000_000},
+		{SKU: "INK", Price: 99, Quantity: 1},
+	}
+
+	cases := []struct {
+		name     string
+		strategy domain.DiscountStrategy
+		template *domain.PromotionTemplate
+		expect   int64
+	}{
+		{
+			// 共 2000000001 件成 666666667 组，最便宜的 INK 和 666666666 件 PEN 免费
+			name:     "buy x get y",
+			strategy: &BuyXGetYStrategy{},
+			template: &domain.PromotionTemplate{DiscountProperties: `{"buy_quantity": 3, "get_quantity": 1}`},
+			expect:   99 + 666_666_666*100,
+		},
+		{
+			// 凑满 1000000000 个循环，每个循环的第二件半价，落单的 INK 不优惠
+			name:     "nth item",
+			strategy: &NthItemStrategy{},
+			template: &domain.PromotionTemplate{DiscountProperties: `{"position_percentages": [100, 50]}`},
+			expect:   1_000_000_000 * 50,
+		},
+		{
+			// 每3件 PEN 一口价 250，优惠50；剩余2件 PEN 与 INK 跨行成组，应付 299，优惠49
+			name:     "bundle price",
+			strategy: &BundlePriceStrategy{},
+			template: &domain.PromotionTemplate{DiscountProperties: `{"bundle_size": 3, "bundle_price": 250}`},
+			expect:   666_666_666*50 + 49,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			offer, err := tc.strategy.Calculate(domain.Fact{Items: items}, tc.template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if offer.Amount != tc.expect {
+				t.Errorf("expected discount %d; got %d", tc.expect, offer.Amount)
+			}
+		})
+	}
+
+	shortfall := threshold{quantity: 3, perSKU: true}.shortfall(domain.Fact{Items: items})
+	if shortfall.Quantity != 0 {
+		t.Errorf("expected no shortfall; got %+v", shortfall)
+	}
+}
+
+func TestNthItemStrategy_SecondUnitHalfPricePerSKU(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeNthItem,
+		DiscountProperties: `{"position_percentages": [100, 50], "per_sku": true}`,
+	}
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "TEE", Price: 1000, Quantity: 2},
+			{SKU: "TEE", Price: 1000, Quantity: 1}, // 同一SKU拆成了两行
+			{SKU: "CAP", Price: 600, Quantity: 1},
+		},
+	}
+
+	offer, err := (&NthItemStrategy{}).Calculate(fact, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 3件TEE中只有1组完整的两件，第二件半价；CAP只有1件不享受优惠
+	if offer.Amount != 500 {
+		t.Errorf("expected discount 500; got %d", offer.Amount)
+	}
+}
+
+func TestPercentageStrategy_OnlyDiscountsScopedItems(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypePercentage,
+		DiscountProperties: `{"percentage": 90, "categories": ["Electronics"], "exclude_brands": ["Apple"]}`,
+	}
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "TV", Price: 100000, Quantity: 1, Category: "Electronics", Brand: "Sony"},
+			{SKU: "PHONE", Price: 500000, Quantity: 1, Category: "Electronics", Brand: "Apple"},
+			{SKU: "BOOK", Price: 5000, Quantity: 2, Category: "Books"},
+		},
+		TotalAmount: 610000,
+	}
+
+	offer, err := (&PercentageStrategy{}).Calculate(fact, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 只有Sony电视在适用范围内：100000 * 10% = 10000
+	if offer.Amount != 10000 {
+		t.Errorf("expected discount 10000; got %d", offer.Amount)
+	}
+}
+
+func TestFixedAmountStrategy_AllocatesLeftoverCentDeterministically(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeFixedAmount,
+		DiscountProperties: `{"threshold": 0, "amount": 1000}`,
+	}
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 100, Quantity: 1},
+			{SKU: "B", Price: 100, Quantity: 1},
+			{SKU: "C", Price: 100, Quantity: 1},
+		},
+		TotalAmount: 300,
+	}
+
+	offer, err := (&FixedAmountStrategy{}).Calculate(fact, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 1000分三等分后余1分，余数相同时补给行号最小的一行
+	expected := []int64{334, 333, 333}
+	if len(offer.Allocations) != len(expected) {
+		t.Fatalf("expected %d allocations; got %d", len(expected), len(offer.Allocations))
+	}
+	for i, a := range offer.Allocations {
+		if a.LineIndex != i || a.Amount != expected[i] {
+			t.Errorf("allocation %d: expected line %d amount %d; got line %d amount %d", i, i, expected[i], a.LineIndex, a.Amount)
+		}
+	}
+}
+
+func TestFixedAmountStrategy_NoAllocationsWithoutItems(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeFixedAmount,
+		DiscountProperties: `{"threshold": 10000, "amount": 1000}`,
+	}
+
+	// 只传总金额时仍然计算优惠，但没有商品行可供分摊
+	offer, err := (&FixedAmountStrategy{}).Calculate(domain.Fact{TotalAmount: 20000}, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if offer.Amount != 1000 || len(offer.Allocations) != 0 {
+		t.Errorf("expected an order-level discount of 1000 without allocations; got %d with %+v", offer.Amount, offer.Allocations)
+	}
+}
+
+func TestBundlePriceStrategy_BundlesMostExpensiveUnitsFirst(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeBundlePrice,
+		DiscountProperties: `{"bundle_size": 3, "bundle_price": 9900, "skus": ["A", "B", "C"]}`,
+	}
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "A", Price: 5000, Quantity: 2},
+			{SKU: "B", Price: 4000, Quantity: 3},
+			{SKU: "C", Price: 3000, Quantity: 2},
+			{SKU: "D", Price: 9000, Quantity: 1},
+		},
+	}
+
+	offer, err := (&BundlePriceStrategy{}).Calculate(fact, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 第一组 A+A+B = 14000 → 优惠4100；第二组 B+B+C = 11000 → 优惠1100；剩余1件C不成组
+	if offer.Amount != 5200 {
+		t.Errorf("expected discount 5200; got %d", offer.Amount)
+	}
+	var allocated int64
+	for _, a := range offer.Allocations {
+		if fact.Items[a.LineIndex].SKU == "D" {
+			t.Errorf("unexpected allocation to out-of-scope line %d", a.LineIndex)
+		}
+		allocated += a.Amount
+	}
+	if allocated != offer.Amount {
+		t.Errorf("expected allocations to sum to %d; got %d", offer.Amount, allocated)
+	}
+}
+
+func TestShippingDiscountStrategy_NeverExceedsShippingFee(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeShippingDiscount,
+		DiscountProperties: `{"threshold": 5000, "amount": 1000, "exclude_regions": ["Xinjiang"]}`,
+	}
+
+	cases := []struct {
+		name     string
+		shipping domain.ShippingContext
+		expect   int64
+	}{
+		{"capped by fee", domain.ShippingContext{Fee: 600, Region: "Shanghai"}, 600},
+		{"partial reduction", domain.ShippingContext{Fee: 1500, Region: "Shanghai"}, 1000},
+		{"excluded region", domain.ShippingContext{Fee: 1500, Region: "Xinjiang"}, 0},
+	}
+
+	strategy := &ShippingDiscountStrategy{}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			offer, err := strategy.Calculate(domain.Fact{TotalAmount: 8000, Shipping: tc.shipping}, template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if offer.Amount != tc.expect {
+				t.Errorf("expected discount %d; got %d", tc.expect, offer.Amount)
+			}
+			if offer.Layer != domain.DiscountLayerShipping {
+				t.Errorf("expected layer %s; got %s", domain.DiscountLayerShipping, offer.Layer)
+			}
+		})
+	}
+}
+
+func TestFormulaStrategy_EvaluatesCelExpression(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeFormula,
+		DiscountProperties: `{"expression": "min(fact.TotalAmount / 10, 3000)"}`,
+	}
+
+	strategy, err := NewStrategyFactory().CreateStrategy(domain.DiscountTypeFormula)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for total, expect := range map[int64]int64{15000: 1500, 80000: 3000} {
+		offer, err := strategy.Calculate(domain.Fact{TotalAmount: total}, template)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if offer.Amount != expect {
+			t.Errorf("total %d: expected discount %d; got %d", total, expect, offer.Amount)
+		}
+	}
+
+	template.DiscountProperties = `{"expression": "fact.TotalAmount > 100"}`
+	if _, err := strategy.Calculate(domain.Fact{TotalAmount: 15000}, template); err == nil {
+		t.Errorf("expected error for non-int formula")
+	}
+}
+
+func TestFormulaStrategy_UsesConfiguredTimezone(t *testing.T) {
+	template := &domain.PromotionTemplate{
+		DiscountType:       domain.DiscountTypeFormula,
+		DiscountProperties: `{"expression": "fact.hourOfDay() == 9 ? 500 : 0"}`,
+	}
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	// UTC 01:00 即北京时间 09:00
+	fact := domain.Fact{
+		TotalAmount: 10000,
+		Environment: domain.EnvironmentContext{Timestamp: time.Date(2026, 11, 11, 1, 0, 0, 0, time.UTC)},
+	}
+
+	strategy, err := NewStrategyFactory(WithTimezone(shanghai)).CreateStrategy(domain.DiscountTypeFormula)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	offer, err := strategy.Calculate(fact, template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if offer.Amount != 500 {
+		t.Errorf("expected the formula to see 09:00 in the configured timezone; got discount %d", offer.Amount)
+	}
+
+	utc, err := NewStrategyFactory(WithTimezone(time.UTC)).CreateStrategy(domain.DiscountTypeFormula)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if offer, err := utc.Calculate(fact, template); err != nil || offer.Amount != 0 {
+		t.Errorf("expected no discount at 01:00 UTC; got %+v (%v)", offer, err)
+	}
+
+	// 校验参数时使用工厂中配置了时区的策略编译表达式，计算时直接命中同一份编译缓存
+	factory := NewStrategyFactory(WithTimezone(shanghai))
+	if err := factory.ValidateProperties(domain.DiscountTypeFormula, template.DiscountProperties); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	configured, _ := factory.CreateStrategy(domain.DiscountTypeFormula)
+	if _, ok := configured.(*FormulaStrategy).programCache.Load("fact.hourOfDay() == 9 ? 500 : 0"); !ok {
+		t.Error("expected the expression to be compiled by the configured formula strategy")
+	}
+}
+
+func TestRewardStrategies_ValidateProperties(t *testing.T) {
+	factory := NewStrategyFactory()
+
+	cases := []struct {
+		name         string
+		discountType domain.DiscountType
+		properties   string
+		valid        bool
+	}{
+		{"points", domain.DiscountTypePoints, `{"threshold": 10000, "points": 200, "point_value": 1}`, true},
+		{"points without points", domain.DiscountTypePoints, `{"threshold": 10000, "points": 0}`, false},
+		{"points with negative value", domain.DiscountTypePoints, `{"points": 200, "point_value": -1}`, false},
+		{"cashback", domain.DiscountTypeCashback, `{"threshold": 10000, "amount": 1000, "value_rate": 80}`, true},
+		{"cashback without amount", domain.DiscountTypeCashback, `{"threshold": 10000, "amount": 0}`, false},
+		{"cashback with rate over 100", domain.DiscountTypeCashback, `{"amount": 1000, "value_rate": 120}`, false},
+		{"gift", domain.DiscountTypeGift, `{"threshold": 10000, "gift_sku": "MUG", "gift_value": 1500}`, true},
+		{"gift without sku", domain.DiscountTypeGift, `{"threshold": 10000, "gift_value": 1500}`, false},
+		{"gift with negative quantity", domain.DiscountTypeGift, `{"gift_sku": "MUG", "gift_quantity": -1}`, false},
+		{"negative threshold", domain.DiscountTypeGift, `{"threshold": -1, "gift_sku": "MUG"}`, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := factory.ValidateProperties(tc.discountType, tc.properties)
+			if tc.valid && err != nil {
+				t.Errorf("expected valid properties; got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("expected validation error")
+			}
+		})
+	}
+}
+
+func TestRewardStrategies_ComputeRewardWithoutDiscount(t *testing.T) {
+	cases := []struct {
+		name         string
+		discountType domain.DiscountType
+		properties   string
+		total        int64
+		expect       *domain.Reward
+	}{
+		{"points below threshold", domain.DiscountTypePoints, `{"threshold": 10000, "points": 200, "point_value": 1}`, 9999, nil},
+		{"points", domain.DiscountTypePoints, `{"threshold": 10000, "points": 200, "point_value": 1}`, 10000,
+			&domain.Reward{Type: domain.RewardTypePoints, Points: 200, MonetaryValue: 200}},
+		{"cashback at face value", domain.DiscountTypeCashback, `{"threshold": 10000, "amount": 1000}`, 12000,
+			&domain.Reward{Type: domain.RewardTypeCashback, Cashback: 1000, MonetaryValue: 1000}},
+		{"cashback discounted by value rate", domain.DiscountTypeCashback, `{"threshold": 10000, "amount": 1000, "value_rate": 80}`, 12000,
+			&domain.Reward{Type: domain.RewardTypeCashback, Cashback: 1000, MonetaryValue: 800}},
+		{"gift defaults to one unit", domain.DiscountTypeGift, `{"threshold": 10000, "gift_sku": "MUG", "gift_value": 1500}`, 10000,
+			&domain.Reward{Type: domain.RewardTypeGift, GiftSKU: "MUG", GiftQuantity: 1, MonetaryValue: 1500}},
+		{"gift with quantity", domain.DiscountTypeGift, `{"threshold": 10000, "gift_sku": "MUG", "gift_quantity": 2, "gift_value": 1500}`, 10000,
+			&domain.Reward{Type: domain.RewardTypeGift, GiftSKU: "MUG", GiftQuantity: 2, MonetaryValue: 3000}},
+	}
+
+	factory := NewStrategyFactory()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy, err := factory.CreateStrategy(tc.discountType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			template := &domain.PromotionTemplate{DiscountType: tc.discountType, DiscountProperties: tc.properties}
+			offer, err := strategy.Calculate(domain.Fact{TotalAmount: tc.total}, template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// 权益类优惠不减少应付金额
+			if offer.Amount != 0 {
+				t.Errorf("expected no discount amount; got %d", offer.Amount)
+			}
+			if tc.expect == nil {
+				if offer.Reward != nil {
+					t.Errorf("expected no reward below threshold; got %+v", offer.Reward)
+				}
+				return
+			}
+			if offer.Reward == nil || *offer.Reward != *tc.expect {
+				t.Errorf("expected reward %+v; got %+v", tc.expect, offer.Reward)
+			}
+			if offer.Value() != tc.expect.MonetaryValue {
+				t.Errorf("expected value %d; got %d", tc.expect.MonetaryValue, offer.Value())
+			}
+		})
+	}
+}
+
+func TestThresholdShortfall_FollowsParsedProperties(t *testing.T) {
+	fact := domain.Fact{
+		Items: []domain.CartItem{
+			{SKU: "COLA", Category: "Drinks", Price: 300, Quantity: 2},
+			{SKU: "CHIPS", Category: "Snacks", Price: 1000, Quantity: 1},
+		},
+		TotalAmount: 1600,
+	}
+
+	cases := []struct {
+		name         string
+		discountType domain.DiscountType
+		properties   string
+		expect       domain.Shortfall
+	}{
+		{"amount threshold", domain.DiscountTypeFixedAmount, `{"threshold": 2000, "amount": 200}`, domain.Shortfall{Amount: 400}},
+		{"scoped amount threshold", domain.DiscountTypeCashback, `{"threshold": 1000, "amount": 100, "categories": ["Drinks"]}`, domain.Shortfall{Amount: 400}},
+		{"lowest tier", domain.DiscountTypeTieredFixedAmount, `{"tiers": [{"threshold": 5000, "amount": 800}, {"threshold": 3000, "amount": 300}]}`, domain.Shortfall{Amount: 1400}},
+		{"every step", domain.DiscountTypeEveryFixedAmount, `{"step": 1000, "amount": 100}`, domain.Shortfall{}},
+		{"free shipping", domain.DiscountTypeFreeShipping, `{"threshold": 9900}`, domain.Shortfall{Amount: 8300}},
+		{"buy x get y", domain.DiscountTypeBuyXGetY, `{"buy_quantity": 3, "get_quantity": 1, "categories": ["Drinks"]}`, domain.Shortfall{Quantity: 1}},
+		{"bundle", domain.DiscountTypeBundlePrice, `{"bundle_size": 5, "bundle_price": 1000}`, domain.Shortfall{Quantity: 2}},
+		{"nth item per sku", domain.DiscountTypeNthItem, `{"position_percentages": [100, 100, 0], "per_sku": true}`, domain.Shortfall{Quantity: 1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy, ok := registrations[tc.discountType].Strategy.(domain.ShortfallStrategy)
+			if !ok {
+				t.Fatalf("expected %s to report shortfalls", tc.discountType)
+			}
+			template := &domain.PromotionTemplate{DiscountType: tc.discountType, DiscountProperties: tc.properties}
+			shortfall, err := strategy.Shortfall(fact, template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if shortfall != tc.expect {
+				t.Errorf("expected shortfall %+v; got %+v", tc.expect, shortfall)
+			}
+		})
+	}
+}
+
+func TestStrategyFactory_ValidatePropertiesRejectsUnknownFields(t *testing.T) {
+	factory := NewStrategyFactory()
+
+	if err := factory.ValidateProperties(domain.DiscountTypeFixedAmount, `{"threshold": 10000, "amount": 2000}`); err != nil {
+		t.Errorf("expected valid properties; got %v", err)
+	}
+	if err := factory.ValidateProperties(domain.DiscountTypeFixedAmount, `{"threshhold": 10000, "amount": 2000}`); err == nil {
+		t.Errorf("expected error for misspelled field")
+	}
+	if err := factory.ValidateProperties(domain.DiscountTypePercentage, `{"percentage": 120}`); err == nil {
+		t.Errorf("expected error for out-of-range percentage")
+	}
+}
+
+func TestStrategyFactory_SupportedTypesDescribeProperties(t *testing.T) {
+	var tiered *StrategyDescriptor
+	for _, d := range NewStrategyFactory().SupportedTypes() {
+		if d.Type == domain.DiscountTypeTieredFixedAmount {
+			tiered = &d
+		}
+	}
+	if tiered == nil {
+		t.Fatalf("expected %s to be registered", domain.DiscountTypeTieredFixedAmount)
+	}
+
+	var tiers *PropertySchema
+	for i, p := range tiered.Properties {
+		if p.Name == "tiers" {
+			tiers = &tiered.Properties[i]
+		}
+	}
+	if tiers == nil || tiers.Type != "array" || tiers.Items == nil || len(tiers.Items.Properties) != 2 {
+		t.Fatalf("expected tiers to be an array of {threshold, amount}; got %+v", tiers)
+	}
+}
@@ -0,0 +1,99 @@
+// promotion-service/internal/infrastructure/discount/tiered_fixed_amount_strategy.go
+package discount
+
+import (
+	"fmt"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func init() {
+	Register(Registration{
+		Type:          domain.DiscountTypeTieredFixedAmount,
+		Name:          "阶梯满减",
+		Strategy:      &TieredFixedAmountStrategy{},
+		NewProperties: func() Properties { return &TieredFixedAmountStrategyProperties{} },
+	})
+}
+
+// AmountTier 定义了阶梯满减中的一档：满 Threshold 减 Amount。
+type AmountTier struct {
+	Threshold int64 `json:"threshold" desc:"该档门槛（单位：分）"`
+	Amount    int64 `json:"amount" desc:"该档优惠金额（单位：分）"`
+}
+
+// TieredFixedAmountStrategyProperties 定义了阶梯满减策略所需的参数结构。
+// 例如 满100减10 / 满200减30 / 满500减100 是同一个活动的三档，而不是三个模板。
+type TieredFixedAmountStrategyProperties struct {
+	ItemScope
+	Tiers []AmountTier `json:"tiers" desc:"各档位，顺序不限"`
+}
+
+// Validate 实现了 Properties 接口。
+func (p *TieredFixedAmountStrategyProperties) Validate() error {
+	if len(p.Tiers) == 0 {
+		return fmt.Errorf("tiered fixed amount properties must contain at least one tier")
+	}
+	seen := make(map[int64]bool, len(p.Tiers))
+	for i, tier := range p.Tiers {
+		if tier.Threshold < 0 {
+			return fmt.Errorf("tiers[%d].threshold must not be negative, got %d", i, tier.Threshold)
+		}
+		if tier.Amount <= 0 {
+			return fmt.Errorf("tiers[%d].amount must be positive, got %d", i, tier.Amount)
+		}
+		if seen[tier.Threshold] {
+			return fmt.Errorf("tiers[%d].threshold %d is duplicated", i, tier.Threshold)
+		}
+		seen[tier.Threshold] = true
+	}
+	return nil
+}
+
+// threshold 实现了 thresholdProperties 接口，门槛为最低一档。
+func (p *TieredFixedAmountStrategyProperties) threshold() threshold {
+	lowest := p.Tiers[0].Threshold
+	for _, tier := range p.Tiers[1:] {
+		if tier.Threshold < lowest {
+			lowest = tier.Threshold
+		}
+	}
+	return threshold{scope: p.ItemScope, amount: lowest}
+}
+
+// TieredFixedAmountStrategy 实现了 domain.DiscountStrategy 接口，用于处理阶梯满减优惠。
+// 它会选取订单金额所能达到的最高一档。
+type TieredFixedAmountStrategy struct {
+	thresholdShortfall
+}
+
+func (s *TieredFixedAmountStrategy) Calculate(fact domain.Fact, template *domain.PromotionTemplate) (*domain.DiscountApplication, error) {
+	var props TieredFixedAmountStrategyProperties
+	if err := parseProperties(template.DiscountProperties, &props); err != nil {
+		return nil, fmt.Errorf("failed to parse tiered fixed amount properties: %w", err)
+	}
+
+	// 选取已达到门槛的最高一档
+	eligibleAmount := props.EligibleAmount(fact)
+	var best *AmountTier
+	for i := range props.Tiers {
+		tier := &props.Tiers[i]
+		if eligibleAmount < tier.Threshold {
+			continue
+		}
+		if best == nil || tier.Threshold > best.Threshold {
+			best = tier
+		}
+	}
+
+	if best == nil {
+		return &domain.DiscountApplication{Amount: 0}, nil // 一档都未达到，不优惠
+	}
+
+	return &domain.DiscountApplication{
+		Amount:       best.Amount,
+		StrategyName: "TieredFixedAmountStrategy",
+		Layer:        domain.DiscountLayerGoods,
+		Allocations:  allocateProportionally(best.Amount, fact, props.ItemScope),
+		Description:  props.describe(fmt.Sprintf("满%d.%02d元减%d.%02d元", best.Threshold/100, best.Threshold%100, best.Amount/100, best.Amount%100)),
+	}, nil
+}
@@ -14,18 +14,22 @@ type PromotionTemplateModel struct {
 	Name            string `gorm:"type:varchar(255);not null;comment:促销名称, 如 '双十一跨店满减'"`
 	Description     string `gorm:"type:text;comment:详细描述"`
 	PromotionType   string `gorm:"type:varchar(50);not null;comment:促销类型, 如 'STORE_COUPON', 'PLATFORM_SALE'"`
+	StoreID         int64  `gorm:"default:0;index;comment:所属店铺ID, 0表示平台级促销"`
 
 	// --- 核心规则与策略字段 ---
-	RuleDefinition     string `gorm:"type:text;comment:规则定义(LHS)"`                                                 // [cite: 199]
+	RuleLanguage       string `gorm:"type:varchar(16);not null;default:'CEL';comment:规则语言, 'CEL' 或 'JSON'"`
+	RuleDefinition     string `gorm:"type:text;comment:规则定义(LHS)"` // [cite: 199]
+	RuleHint           string `gorm:"type:varchar(255);comment:面向用户的规则说明, 如 '仅限VIP'"`
 	DiscountType       string `gorm:"type:varchar(50);not null;comment:优惠类型(RHS), 如 'FIXED_AMOUNT', 'PERCENTAGE'"` // [cite: 189]
 	DiscountProperties string `gorm:"type:text;comment:优惠策略需要的参数, 如满减门槛、折扣率等"`
 
 	// --- 生命周期与元数据 ---
 	StartDate   time.Time `gorm:"comment:活动生效时间"`
 	EndDate     time.Time `gorm:"comment:活动失效时间"`
-	IsExclusive bool      `gorm:"default:true;comment:是否与其它优惠互斥"`   // [cite: 192]
-	Priority    int       `gorm:"default:0;comment:优先级, 数字越大优先级越高"` // [cite: 193]
-	IsActive    bool      `gorm:"default:true;comment:当前版本是否激活"`    // [cite: 194]
+	IsExclusive bool      `gorm:"not null;comment:是否与其它优惠互斥"`               // [cite: 192]
+	IsAutomatic bool      `gorm:"default:false;index;comment:是否为无需领券的自动促销"` // 全场活动等无需用户持券
+	Priority    int       `gorm:"default:0;comment:优先级, 数字越大优先级越高"`         // [cite: 193]
+	IsActive    bool      `gorm:"default:true;comment:当前版本是否激活"`            // [cite: 194]
 
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
@@ -50,3 +54,15 @@ type UserCouponModel struct {
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
+
+// StackingPolicyModel 对应于数据库中的 `stacking_policies` 表
+// 每种促销类型最多一条记录，定义该类型在一笔订单中的叠加规则。
+type StackingPolicyModel struct {
+	ID            int64  `gorm:"primaryKey"`
+	PromotionType string `gorm:"type:varchar(50);uniqueIndex;not null;comment:促销类型, 如 'STORE_COUPON', 'PLATFORM_SALE'"`
+	MaxCount      int    `gorm:"default:0;comment:同类型最多可同时使用的张数, 0表示不限制"`
+	StackableWith string `gorm:"type:varchar(500);comment:可叠加的其它促销类型, 逗号分隔, '*'表示任意类型"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -2,6 +2,7 @@ package infrastructure
 
 import (
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"strings"
 )
 
 // --- PromotionTemplate Mappers ---
@@ -17,12 +18,16 @@ func toDomainPromotionTemplate(model *PromotionTemplateModel) *domain.PromotionT
 		Name:               model.Name,
 		Description:        model.Description,
 		PromotionType:      model.PromotionType,
+		StoreID:            model.StoreID,
+		RuleLanguage:       domain.RuleLanguage(model.RuleLanguage),
 		RuleDefinition:     model.RuleDefinition,
+		RuleHint:           model.RuleHint,
 		DiscountType:       domain.DiscountType(model.DiscountType),
 		DiscountProperties: model.DiscountProperties,
 		StartDate:          model.StartDate,
 		EndDate:            model.EndDate,
 		IsExclusive:        model.IsExclusive,
+		IsAutomatic:        model.IsAutomatic,
 		Priority:           model.Priority,
 		IsActive:           model.IsActive,
 		CreatedAt:          model.CreatedAt,
@@ -41,12 +46,16 @@ func toGormPromotionTemplate(domain *domain.PromotionTemplate) *PromotionTemplat
 		Name:               domain.Name,
 		Description:        domain.Description,
 		PromotionType:      domain.PromotionType,
+		StoreID:            domain.StoreID,
+		RuleLanguage:       string(domain.RuleLanguage),
 		RuleDefinition:     domain.RuleDefinition,
+		RuleHint:           domain.RuleHint,
 		DiscountType:       string(domain.DiscountType),
 		DiscountProperties: domain.DiscountProperties,
 		StartDate:          domain.StartDate,
 		EndDate:            domain.EndDate,
 		IsExclusive:        domain.IsExclusive,
+		IsAutomatic:        domain.IsAutomatic,
 		Priority:           domain.Priority,
 		IsActive:           domain.IsActive,
 		CreatedAt:          domain.CreatedAt,
@@ -91,3 +100,36 @@ func toGormUserCoupon(domain *domain.UserCoupon) *UserCouponModel {
 		UpdatedAt:  domain.UpdatedAt,
 	}
 }
+
+// --- StackingPolicy Mappers ---
+
+func toDomainStackingPolicy(model *StackingPolicyModel) *domain.StackingPolicy {
+	if model == nil {
+		return nil
+	}
+	policy := &domain.StackingPolicy{
+		ID:            model.ID,
+		PromotionType: model.PromotionType,
+		MaxCount:      model.MaxCount,
+		CreatedAt:     model.CreatedAt,
+		UpdatedAt:     model.UpdatedAt,
+	}
+	if model.StackableWith != "" {
+		policy.StackableWith = strings.Split(model.StackableWith, ",")
+	}
+	return policy
+}
+
+func toGormStackingPolicy(domain *domain.StackingPolicy) *StackingPolicyModel {
+	if domain == nil {
+		return nil
+	}
+	return &StackingPolicyModel{
+		ID:            domain.ID,
+		PromotionType: domain.PromotionType,
+		MaxCount:      domain.MaxCount,
+		StackableWith: strings.Join(domain.StackableWith, ","),
+		CreatedAt:     domain.CreatedAt,
+		UpdatedAt:     domain.UpdatedAt,
+	}
+}
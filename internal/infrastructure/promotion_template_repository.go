@@ -71,6 +71,32 @@ func (r *gormPromotionTemplateRepository) FindAllActiveTemplates(ctx context.Con
 	return templates, nil
 }
 
+func (r *gormPromotionTemplateRepository) FindActiveAutomatic(ctx context.Context) ([]*domain.PromotionTemplate, error) {
+	var models []*PromotionTemplateModel
+	if err := r.db.WithContext(ctx).Where("is_active = ? AND is_automatic = ? AND start_date <= NOW() AND end_date >= NOW()", true, true).Order("id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	var templates []*domain.PromotionTemplate
+	for _, model := range models {
+		templates = append(templates, toDomainPromotionTemplate(model))
+	}
+	return templates, nil
+}
+
+func (r *gormPromotionTemplateRepository) FindActiveByRuleLanguage(ctx context.Context, language domain.RuleLanguage) ([]*domain.PromotionTemplate, error) {
+	var models []*PromotionTemplateModel
+	if err := r.db.WithContext(ctx).Where("is_active = ? AND rule_language = ?", true, string(language)).Order("id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	var templates []*domain.PromotionTemplate
+	for _, model := range models {
+		templates = append(templates, toDomainPromotionTemplate(model))
+	}
+	return templates, nil
+}
+
 func (r *gormPromotionTemplateRepository) Create(ctx context.Context, template *domain.PromotionTemplate) error {
 	model := toGormPromotionTemplate(template)
 	return r.db.WithContext(ctx).Create(model).Error
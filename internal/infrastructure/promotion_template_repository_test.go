@@ -0,0 +1,66 @@
+// internal/infrastructure/promotion_template_repository_test.go
+package infrastructure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// newDryRunDB 返回一个只生成 SQL、不连接数据库的 GORM 实例，
+// 并通过回调把每条 INSERT 语句按列名记录到 inserted 中。
+func newDryRunDB(t *testing.T, inserted map[string]interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:root@tcp(127.0.0.1:3306)/test?charset=utf8mb4&parseTime=True&loc=Local",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("failed to open dry run database: %v", err)
+	}
+	err = db.Callback().Create().After("gorm:create").Register("test:capture_insert", func(tx *gorm.DB) {
+		sql := tx.Statement.SQL.String()
+		start, end := strings.Index(sql, "("), strings.Index(sql, ")")
+		for i, column := range strings.Split(sql[start+1:end], ",") {
+			inserted[strings.Trim(column, "`")] = tx.Statement.Vars[i]
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+	return db
+}
+
+func TestGormPromotionTemplateRepository_CreatePersistsNonExclusiveTemplate(t *testing.T) {
+	inserted := map[string]interface{}{}
+	repo := NewGormPromotionTemplateRepository(newDryRunDB(t, inserted))
+
+	template := &domain.PromotionTemplate{
+		TemplateGroupID: "group-stackable",
+		Version:         1,
+		Name:            "可叠加的满100减10",
+		PromotionType:   domain.PromotionTypePlatformSale,
+		DiscountType:    domain.DiscountTypeFixedAmount,
+		IsExclusive:     false,
+		IsActive:        true,
+	}
+	if err := repo.Create(context.Background(), template); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 零值字段带 default 标签时 GORM 会改写成数据库默认值，非互斥模板必须原样写入 false
+	if v, ok := inserted["is_exclusive"]; !ok || v != false {
+		t.Fatalf("expected is_exclusive=false to be inserted; got %v (present: %v)", v, ok)
+	}
+
+	// 按写入的列值构造模型，读回领域对象后仍是非互斥的
+	model := &PromotionTemplateModel{IsExclusive: inserted["is_exclusive"].(bool), IsActive: inserted["is_active"].(bool)}
+	if got := toDomainPromotionTemplate(model); got.IsExclusive || !got.IsActive {
+		t.Errorf("expected a non-exclusive active template after reading back; got %+v", got)
+	}
+}
@@ -6,32 +6,82 @@ import (
 	"github.com/google/cel-go/ext"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 
 	"github.com/google/cel-go/cel"
 )
 
-// CelRuleEngine 是 domain.RuleEngine 接口基于 cel-go 的实现
+// CelRuleEngine 是 domain.RuleEngine 接口基于 cel-go 的实现，负责 CEL 语言的规则
 type CelRuleEngine struct {
 	env          *cel.Env
 	programCache *sync.Map // 用于缓存已编译的规则程序，提高性能
 }
 
-// NewCelRuleEngine 创建并初始化一个新的 CEL 规则引擎
-// 这是大厂实践中的标准做法：预先定义好环境和类型，确保类型安全和性能。
-func NewCelRuleEngine() (domain.RuleEngine, error) {
-	env, err := cel.NewEnv(
+// NewFactEnv 创建以 domain.Fact 为输入的 cel-go 环境。
+// 规则引擎和公式类优惠策略共用这一套类型声明，保证两边对 fact 的理解完全一致；
+// 调用方可以通过 opts 追加自己需要的函数或变量。
+func NewFactEnv(opts ...cel.EnvOption) (*cel.Env, error) {
+	envOpts := []cel.EnvOption{
 		// 注册 domain.Fact 类型
 		ext.NativeTypes(
 			reflect.TypeOf(domain.Fact{}), reflect.TypeOf(domain.UserContext{}),
-			reflect.TypeOf(domain.CartItem{}), reflect.TypeOf(domain.EnvironmentContext{})),
+			reflect.TypeOf(domain.CartItem{}), reflect.TypeOf(domain.EnvironmentContext{}),
+			reflect.TypeOf(domain.ShippingContext{})),
 		// 声明 fact 变量
 		cel.Variable("fact", cel.ObjectType("domain.Fact")),
-	)
+	}
+	env, err := cel.NewEnv(append(envOpts, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cel-go environment: %w", err)
 	}
+	return env, nil
+}
+
+// CelOption 用于定制 CEL 规则引擎。
+type CelOption func(*celConfig)
+
+type celConfig struct {
+	location *time.Location
+	envOpts  []cel.EnvOption
+}
+
+// WithTimezone 设置 hourOfDay、dayOfWeek 等函数计算时使用的时区，默认为服务器本地时区。
+func WithTimezone(loc *time.Location) CelOption {
+	return func(c *celConfig) { c.location = loc }
+}
+
+// LoadTimezone 按 IANA 名称（如 "Asia/Shanghai"）加载规则使用的时区，名称为空时使用服务器本地时区。
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// WithEnvOptions 向规则环境追加自定义的函数或变量。
+func WithEnvOptions(opts ...cel.EnvOption) CelOption {
+	return func(c *celConfig) { c.envOpts = append(c.envOpts, opts...) }
+}
+
+// NewCelRuleEngine 创建并初始化一个新的 CEL 规则引擎
+// 这是大厂实践中的标准做法：预先定义好环境和类型，确保类型安全和性能。
+// 环境中注册了 PromotionFunctions 提供的促销专用函数。
+func NewCelRuleEngine(opts ...CelOption) (domain.RuleEngine, error) {
+	cfg := &celConfig{location: time.Local}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	env, err := NewFactEnv(append([]cel.EnvOption{PromotionFunctions(cfg.location)}, cfg.envOpts...)...)
+	if err != nil {
+		return nil, err
+	}
 
 	return &CelRuleEngine{
 		env:          env,
@@ -40,39 +90,20 @@ func NewCelRuleEngine() (domain.RuleEngine, error) {
 }
 
 // Evaluate 实现了 domain.RuleEngine 接口
-func (e *CelRuleEngine) Evaluate(ruleDefinition string, fact domain.Fact) (bool, error) {
+func (e *CelRuleEngine) Evaluate(rule domain.PromotionRule, fact domain.Fact) (bool, error) {
+	ruleDefinition := rule.Definition
 	// 如果规则为空，则直接认为是满足条件，这对于无门槛券等场景很实用。
 	if ruleDefinition == "" {
 		return true, nil
 	}
 
-	var prg cel.Program
-	// 2. 检查缓存中是否已有编译好的程序
-	if cachedPrg, found := e.programCache.Load(ruleDefinition); found {
-		prg = cachedPrg.(cel.Program)
-	} else {
-		// 3. 如果缓存未命中，则编译规则并存入缓存
-		ast, issues := e.env.Compile(ruleDefinition)
-		if issues != nil && issues.Err() != nil {
-			// 编译时错误，说明规则本身有语法问题
-			return false, fmt.Errorf("rule compilation failed: %w", issues.Err())
-		}
-
-		// 检查编译后的表达式输出类型是否为 bool
-		if !ast.OutputType().IsExactType(cel.BoolType) {
-			return false, fmt.Errorf("rule must return a boolean value, but got %s", ast.OutputType())
-		}
-
-		var err error
-		prg, err = e.env.Program(ast)
-		if err != nil {
-			return false, fmt.Errorf("program creation failed: %w", err)
-		}
-		// 存入缓存
-		e.programCache.Store(ruleDefinition, prg)
+	// 1. 获取编译好的程序，优先使用缓存
+	prg, err := e.program(ruleDefinition)
+	if err != nil {
+		return false, err
 	}
 
-	// 4. 执行评估
+	// 2. 执行评估
 	out, _, err := prg.Eval(map[string]interface{}{
 		"fact": &fact, // 将 fact 数据传入
 	})
@@ -81,7 +112,7 @@ func (e *CelRuleEngine) Evaluate(ruleDefinition string, fact domain.Fact) (bool,
 		return false, fmt.Errorf("rule evaluation failed: %w", err)
 	}
 
-	// 5. 返回结果
+	// 3. 返回结果
 	// CEL 的布尔值需要类型断言
 	result, ok := out.Value().(bool)
 	if !ok {
@@ -90,3 +121,40 @@ func (e *CelRuleEngine) Evaluate(ruleDefinition string, fact domain.Fact) (bool,
 
 	return result, nil
 }
+
+// Compile 实现了 domain.RuleEngine 接口，编译成功的程序会被缓存，供后续评估直接使用
+func (e *CelRuleEngine) Compile(rule domain.PromotionRule) error {
+	if rule.Definition == "" {
+		return nil
+	}
+	_, err := e.program(rule.Definition)
+	return err
+}
+
+// program 返回规则对应的已编译程序
+func (e *CelRuleEngine) program(ruleDefinition string) (cel.Program, error) {
+	// 检查缓存中是否已有编译好的程序
+	if cachedPrg, found := e.programCache.Load(ruleDefinition); found {
+		return cachedPrg.(cel.Program), nil
+	}
+
+	// 如果缓存未命中，则编译规则并存入缓存
+	ast, issues := e.env.Compile(ruleDefinition)
+	if issues != nil && issues.Err() != nil {
+		// 编译时错误，说明规则本身有语法问题
+		return nil, fmt.Errorf("rule compilation failed: %w", issues.Err())
+	}
+
+	// 检查编译后的表达式输出类型是否为 bool
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("rule must return a boolean value, but got %s", ast.OutputType())
+	}
+
+	prg, err := e.env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("program creation failed: %w", err)
+	}
+	// 存入缓存
+	e.programCache.Store(ruleDefinition, prg)
+	return prg, nil
+}
@@ -0,0 +1,38 @@
+// internal/infrastructure/rule/cel_engine_test.go
+package rule
+
+import (
+	"testing"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestCelRuleEngine_CompileRejectsInvalidRules(t *testing.T) {
+	engine, err := NewCelRuleEngine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := engine.Compile(domain.PromotionRule{Definition: `fact.User.IsVip && fact.TotalAmount >= 10000`}); err != nil {
+		t.Errorf("expected a valid rule; got %v", err)
+	}
+	if err := engine.Compile(domain.PromotionRule{}); err != nil {
+		t.Errorf("expected an empty rule to compile; got %v", err)
+	}
+
+	rules := []string{
+		`fact.User.IsVip &&`,                               // 语法错误
+		`fact.User.Unknown == true`,                        // 不存在的字段
+		`fact.TotalAmount + 1`,                             // 结果不是布尔值
+		`fact.TotalAmount > "100"`,                         // 类型不匹配
+		`fact.Items.sumBy("Category", 1)`,                  // 没有匹配的函数重载
+		`fact.Items.sumBy("Price", "1") > 0`,               // sumBy 不支持的字段
+		`fact.Items.sumBy("Colour", "red") > 0`,            // sumBy 不存在的字段
+		`fact.Items.sumBy(fact.User.Labels[0], "red") > 0`, // sumBy 的字段不是字面量
+	}
+	for _, r := range rules {
+		if err := engine.Compile(domain.PromotionRule{Language: domain.RuleLanguageCEL, Definition: r}); err == nil {
+			t.Errorf("expected a compile error for %s", r)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// promotion-service/internal/infrastructure/rule/cel_functions.go
+package rule
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+var (
+	factCelType     = cel.ObjectType("domain.Fact")
+	cartItemCelType = cel.ObjectType("domain.CartItem")
+	cartItemsType   = reflect.TypeOf([]domain.CartItem{})
+
+	// sumByFields 是 sumBy 可以按其筛选的商品字段
+	sumByFields = map[string]bool{"SKU": true, "Category": true, "Brand": true}
+)
+
+// PromotionFunctions 返回促销规则专用的 CEL 函数库，让规则读起来接近业务语言，便于运营审核：
+//
+//	fact.Items.sumBy("Category", "Electronics") // 指定品类商品扣除已分摊优惠后的小计之和（单位：分），字段可以是 SKU、Category、Brand
+//	fact.itemCount()                            // 购物车商品总件数，也可以对筛选后的列表使用，如 fact.Items.filter(i, i.Brand == "A").itemCount()
+//	fact.hasSku("SKU001")                       // 购物车中是否有该SKU
+//	fact.hasLabel("new_user")                   // 用户是否带有该标签
+//	fact.hourOfDay()                            // 下单时间在 loc 时区的小时数, 0-23
+//	fact.dayOfWeek()                            // 下单时间在 loc 时区的星期, 0 表示周日，与 CEL 的 getDayOfWeek 一致
+//	withinRange(fact.Environment.Timestamp, "2026-11-11T00:00:00+08:00", "2026-11-11T23:59:59+08:00") // 时间是否落在闭区间内，边界也可以是 timestamp
+//
+// 下单时间取 fact.Environment.Timestamp，调用方未传入时按当前时间计算。
+// sumBy 的字段必须是字符串字面量，编译时即检查，不支持的字段不会等到求值时才报错。
+func PromotionFunctions(loc *time.Location) cel.EnvOption {
+	return cel.Lib(&promotionLibrary{loc: loc})
+}
+
+// promotionLibrary 实现了 cel.Library 接口。
+type promotionLibrary struct {
+	loc *time.Location
+}
+
+func (l *promotionLibrary) LibraryName() string {
+	return "nexus.promotion"
+}
+
+func (l *promotionLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+func (l *promotionLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.ASTValidators(sumByFieldValidator{}),
+		cel.Function("sumBy",
+			cel.MemberOverload("list_cart_item_sum_by_string_string",
+				[]*cel.Type{cel.ListType(cartItemCelType), cel.StringType, cel.StringType}, cel.IntType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					items, err := toCartItems(args[0])
+					if err != nil {
+						return types.NewErr("sumBy: %v", err)
+					}
+					return sumBy(items, string(args[1].(types.String)), string(args[2].(types.String)))
+				}))),
+		cel.Function("itemCount",
+			cel.MemberOverload("fact_item_count", []*cel.Type{factCelType}, cel.IntType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					fact, err := toFact(arg)
+					if err != nil {
+						return types.NewErr("itemCount: %v", err)
+					}
+					return itemCount(fact.Items)
+				})),
+			cel.MemberOverload("list_cart_item_item_count", []*cel.Type{cel.ListType(cartItemCelType)}, cel.IntType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					items, err := toCartItems(arg)
+					if err != nil {
+						return types.NewErr("itemCount: %v", err)
+					}
+					return itemCount(items)
+				}))),
+		cel.Function("hasSku",
+			cel.MemberOverload("fact_has_sku_string", []*cel.Type{factCelType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					fact, err := toFact(lhs)
+					if err != nil {
+						return types.NewErr("hasSku: %v", err)
+					}
+					for _, item := range fact.Items {
+						if item.SKU == string(rhs.(types.String)) {
+							return types.True
+						}
+					}
+					return types.False
+				}))),
+		cel.Function("hasLabel",
+			cel.MemberOverload("fact_has_label_string", []*cel.Type{factCelType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+					fact, err := toFact(lhs)
+					if err != nil {
+						return types.NewErr("hasLabel: %v", err)
+					}
+					for _, label := range fact.User.Labels {
+						if label == string(rhs.(types.String)) {
+							return types.True
+						}
+					}
+					return types.False
+				}))),
+		cel.Function("hourOfDay",
+			cel.MemberOverload("fact_hour_of_day", []*cel.Type{factCelType}, cel.IntType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					fact, err := toFact(arg)
+					if err != nil {
+						return types.NewErr("hourOfDay: %v", err)
+					}
+					return types.Int(l.orderTime(fact).Hour())
+				}))),
+		cel.Function("dayOfWeek",
+			cel.MemberOverload("fact_day_of_week", []*cel.Type{factCelType}, cel.IntType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					fact, err := toFact(arg)
+					if err != nil {
+						return types.NewErr("dayOfWeek: %v", err)
+					}
+					return types.Int(l.orderTime(fact).Weekday())
+				}))),
+		cel.Function("withinRange",
+			cel.Overload("within_range_timestamp_timestamp_timestamp",
+				[]*cel.Type{cel.TimestampType, cel.TimestampType, cel.TimestampType}, cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					return withinRange(args[0].(types.Timestamp).Time, args[1].(types.Timestamp).Time, args[2].(types.Timestamp).Time)
+				})),
+			cel.Overload("within_range_timestamp_string_string",
+				[]*cel.Type{cel.TimestampType, cel.StringType, cel.StringType}, cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					start, err := time.Parse(time.RFC3339, string(args[1].(types.String)))
+					if err != nil {
+						return types.NewErr("withinRange: invalid start: %v", err)
+					}
+					end, err := time.Parse(time.RFC3339, string(args[2].(types.String)))
+					if err != nil {
+						return types.NewErr("withinRange: invalid end: %v", err)
+					}
+					return withinRange(args[0].(types.Timestamp).Time, start, end)
+				}))),
+	}
+}
+
+// orderTime 返回下单时间在配置时区下的表示。
+func (l *promotionLibrary) orderTime(fact domain.Fact) time.Time {
+	t := fact.Environment.Timestamp
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.In(l.loc)
+}
+
+// sumByFieldValidator 在编译时检查 sumBy 的字段参数是否为 sumByFields 中的字符串字面量。
+type sumByFieldValidator struct{}
+
+func (sumByFieldValidator) Name() string {
+	return "nexus.promotion.validator.sumBy"
+}
+
+func (sumByFieldValidator) Validate(_ *cel.Env, _ cel.ValidatorConfig, a *ast.AST, iss *cel.Issues) {
+	for _, call := range ast.MatchDescendants(ast.NavigateAST(a), ast.FunctionMatcher("sumBy")) {
+		args := call.AsCall().Args()
+		if len(args) == 0 {
+			continue
+		}
+		field := args[0]
+		var name string
+		if field.Kind() == ast.LiteralKind {
+			name, _ = field.AsLiteral().Value().(string)
+		}
+		if name == "" {
+			iss.ReportErrorAtID(field.ID(), "sumBy: field must be a string literal, one of SKU, Category or Brand")
+			continue
+		}
+		if _, ok := sumByField(name); !ok {
+			iss.ReportErrorAtID(field.ID(), "sumBy: unsupported field %q, expected SKU, Category or Brand", name)
+		}
+	}
+}
+
+// sumByField 返回 sumBy 字段名对应的商品字段，首字母大小写不敏感，与 JSON 规则的路径规则一致。
+func sumByField(field string) (reflect.StructField, bool) {
+	name := field
+	if name != "" {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	if !sumByFields[name] {
+		return reflect.StructField{}, false
+	}
+	return reflect.TypeOf(domain.CartItem{}).FieldByName(name)
+}
+
+// sumBy 累加 field 字段等于 value 的商品扣除已分摊优惠后的小计。
+func sumBy(items []domain.CartItem, field, value string) ref.Val {
+	f, ok := sumByField(field)
+	if !ok {
+		return types.NewErr("sumBy: unsupported field %q, expected SKU, Category or Brand", field)
+	}
+
+	var total int64
+	for _, item := range items {
+		if reflect.ValueOf(item).FieldByIndex(f.Index).String() == value {
+			total += item.Subtotal()
+		}
+	}
+	return types.Int(total)
+}
+
+func itemCount(items []domain.CartItem) ref.Val {
+	var count int64
+	for _, item := range items {
+		count += int64(item.Quantity)
+	}
+	return types.Int(count)
+}
+
+func withinRange(t, start, end time.Time) ref.Val {
+	return types.Bool(!t.Before(start) && !t.After(end))
+}
+
+func toFact(v ref.Val) (domain.Fact, error) {
+	native, err := v.ConvertToNative(reflect.TypeOf(domain.Fact{}))
+	if err != nil {
+		return domain.Fact{}, err
+	}
+	fact, ok := native.(domain.Fact)
+	if !ok {
+		return domain.Fact{}, fmt.Errorf("unexpected fact type %T", native)
+	}
+	return fact, nil
+}
+
+func toCartItems(v ref.Val) ([]domain.CartItem, error) {
+	native, err := v.ConvertToNative(cartItemsType)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := native.([]domain.CartItem)
+	if !ok {
+		return nil, fmt.Errorf("unexpected items type %T", native)
+	}
+	return items, nil
+}
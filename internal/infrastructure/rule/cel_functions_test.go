@@ -0,0 +1,50 @@
+// internal/infrastructure/rule/cel_functions_test.go
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestCelRuleEngine_PromotionFunctions(t *testing.T) {
+	shanghai := time.FixedZone("Asia/Shanghai", 8*3600)
+	engine, err := NewCelRuleEngine(WithTimezone(shanghai))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fact := domain.Fact{
+		User: domain.UserContext{Labels: []string{"new_user"}},
+		Items: []domain.CartItem{
+			{SKU: "PHONE-1", Price: 300000, Quantity: 1, Category: "Electronics", Discount: 10000},
+			{SKU: "CABLE-1", Price: 2000, Quantity: 3, Category: "Electronics"},
+			{SKU: "BOOK-1", Price: 5000, Quantity: 2, Category: "Books"},
+		},
+		// UTC 周日 16:30，即上海时间周一 00:30
+		Environment: domain.EnvironmentContext{Timestamp: time.Date(2026, 11, 15, 16, 30, 0, 0, time.UTC)},
+	}
+
+	rules := []string{
+		`fact.Items.sumBy("Category", "Electronics") == 296000`,
+		`fact.Items.sumBy("category", "Toys") == 0`,
+		`fact.itemCount() == 6`,
+		`fact.Items.filter(i, i.Category == "Books").itemCount() == 2`,
+		`fact.hasSku("BOOK-1") && !fact.hasSku("BOOK-2")`,
+		`fact.hasLabel("new_user") && !fact.hasLabel("vip")`,
+		`fact.hourOfDay() == 0`,
+		`fact.dayOfWeek() == 1`,
+		`withinRange(fact.Environment.Timestamp, "2026-11-16T00:00:00+08:00", "2026-11-16T23:59:59+08:00")`,
+		`!withinRange(fact.Environment.Timestamp, timestamp("2026-11-11T00:00:00Z"), timestamp("2026-11-11T23:59:59Z"))`,
+	}
+	for _, r := range rules {
+		match, err := engine.Evaluate(domain.PromotionRule{Definition: r}, fact)
+		if err != nil || !match {
+			t.Errorf("%s: expected match; got %v, %v", r, match, err)
+		}
+	}
+
+	if _, err := engine.Evaluate(domain.PromotionRule{Definition: `fact.Items.sumBy("Price", "1") > 0`}, fact); err == nil {
+		t.Error("expected an error for summing by a non-string field")
+	}
+}
@@ -6,36 +6,58 @@ import (
 	"fmt"
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Condition represents a single rule condition (e.g., "user.isVip", "equal", true).
+// 对数组字段使用 some/every/none 时，Value 是一个嵌套的规则节点，其中的 fact 路径相对于数组元素，
+// 例如 {"fact": "Items", "operator": "some", "value": {"all": [{"fact": "Category", "operator": "equal", "value": "fresh"}]}}。
 type Condition struct {
 	Fact     string      `json:"fact"`
 	Operator string      `json:"operator"`
 	Value    interface{} `json:"value"`
 }
 
-// RuleGroup represents a group of conditions combined with "all" or "any".
+// RuleGroup represents a group of conditions combined with "all" or "any", or a negated node with "not".
 type RuleGroup struct {
 	All []json.RawMessage `json:"all"`
 	Any []json.RawMessage `json:"any"`
+	Not json.RawMessage   `json:"not"`
 }
 
-// JSONRuleEngineAdapter 是 domain.RuleEngine 接口的一个新的、自包含的实现。
+// JSONRuleEngineAdapter 是 domain.RuleEngine 接口的一个新的、自包含的实现，负责 JSON 语言的规则。
 // 它不依赖任何外部库，直接解析和评估规则。
-type JSONRuleEngineAdapter struct{}
+type JSONRuleEngineAdapter struct {
+	ruleCache *sync.Map // 用于缓存已解析的规则树，提高性能
+}
 
 // NewJSONRuleEngineAdapter 创建一个新的规则引擎适配器实例。
 func NewJSONRuleEngineAdapter() *JSONRuleEngineAdapter {
-	return &JSONRuleEngineAdapter{}
+	return &JSONRuleEngineAdapter{ruleCache: &sync.Map{}}
+}
+
+// compiledNode 是解析并校验过的规则节点，求值时不再重复解析 JSON 或编译正则。
+type compiledNode struct {
+	all       []*compiledNode
+	any       []*compiledNode
+	not       *compiledNode
+	condition *Condition
+	pattern   *regexp.Regexp // regex 操作符预编译的正则
+	nested    *compiledNode  // some/every/none 的嵌套规则
 }
 
 // Evaluate 实现了 domain.RuleEngine 接口，用于执行规则评估。
-func (a *JSONRuleEngineAdapter) Evaluate(ruleDefinition string, fact domain.Fact) (bool, error) {
-	var raw json.RawMessage
-	if err := json.Unmarshal([]byte(ruleDefinition), &raw); err != nil {
-		return false, fmt.Errorf("failed to unmarshal rule definition: %w", err)
+func (a *JSONRuleEngineAdapter) Evaluate(rule domain.PromotionRule, fact domain.Fact) (bool, error) {
+	// 与 CEL 引擎一致，空规则视为无条件满足
+	if rule.Definition == "" {
+		return true, nil
+	}
+	node, err := a.compile(rule.Definition)
+	if err != nil {
+		return false, err
 	}
 
 	factMap, err := structToMap(fact)
@@ -43,51 +65,221 @@ func (a *JSONRuleEngineAdapter) Evaluate(ruleDefinition string, fact domain.Fact
 		return false, fmt.Errorf("failed to convert fact to map: %w", err)
 	}
 
-	return a.evaluateNode(raw, factMap)
+	return a.evaluateNode(node, factMap)
+}
+
+// Compile 实现了 domain.RuleEngine 接口，检查规则结构、事实路径、操作符及其取值是否合法。
+// 编译成功的规则树会被缓存，供后续评估直接使用。
+func (a *JSONRuleEngineAdapter) Compile(rule domain.PromotionRule) error {
+	if rule.Definition == "" {
+		return nil
+	}
+	_, err := a.compile(rule.Definition)
+	return err
+}
+
+// compile 返回规则定义对应的已解析规则树
+func (a *JSONRuleEngineAdapter) compile(definition string) (*compiledNode, error) {
+	if cached, found := a.ruleCache.Load(definition); found {
+		return cached.(*compiledNode), nil
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(definition), &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rule definition: %w", err)
+	}
+	node, err := a.compileNode(raw, reflect.TypeOf(domain.Fact{}))
+	if err != nil {
+		return nil, err
+	}
+	a.ruleCache.Store(definition, node)
+	return node, nil
 }
 
-// evaluateNode 递归地评估一个JSON节点（可以是条件组或单个条件）。
-func (a *JSONRuleEngineAdapter) evaluateNode(node json.RawMessage, factMap map[string]interface{}) (bool, error) {
-	// 尝试解析为条件组 (all/any)
+// compileNode 递归地解析并检查一个JSON节点。
+// root 是节点中 fact 路径的起点类型，数组量词内部为数组元素的类型。
+func (a *JSONRuleEngineAdapter) compileNode(raw json.RawMessage, root reflect.Type) (*compiledNode, error) {
 	var group RuleGroup
-	if json.Unmarshal(node, &group) == nil {
-		if len(group.All) > 0 {
+	if json.Unmarshal(raw, &group) == nil {
+		if len(group.All) > 0 || len(group.Any) > 0 {
+			node := &compiledNode{}
 			for _, subNode := range group.All {
-				match, err := a.evaluateNode(subNode, factMap)
+				compiled, err := a.compileNode(subNode, root)
 				if err != nil {
-					return false, err
-				}
-				if !match {
-					return false, nil // "all" 逻辑，一旦有一个不匹配，整个组就不匹配
+					return nil, err
 				}
+				node.all = append(node.all, compiled)
 			}
-			return true, nil // 所有都匹配
-		}
-		if len(group.Any) > 0 {
 			for _, subNode := range group.Any {
-				match, err := a.evaluateNode(subNode, factMap)
+				compiled, err := a.compileNode(subNode, root)
 				if err != nil {
-					return false, err
-				}
-				if match {
-					return true, nil // "any" 逻辑，一旦有一个匹配，整个组就匹配
+					return nil, err
 				}
+				node.any = append(node.any, compiled)
+			}
+			return node, nil
+		}
+		if len(group.Not) > 0 {
+			not, err := a.compileNode(group.Not, root)
+			if err != nil {
+				return nil, err
 			}
-			return false, nil // 没有任何一个匹配
+			return &compiledNode{not: not}, nil
 		}
 	}
 
-	// 尝试解析为单个条件
 	var condition Condition
-	if json.Unmarshal(node, &condition) == nil {
-		return a.evaluateCondition(&condition, factMap)
+	if err := json.Unmarshal(raw, &condition); err != nil || condition.Fact == "" {
+		return nil, fmt.Errorf("invalid rule structure: %s", string(raw))
+	}
+	factType, err := resolveFactType(condition.Fact, root)
+	if err != nil {
+		return nil, err
+	}
+	if !supportedOperators[condition.Operator] {
+		return nil, fmt.Errorf("unsupported operator: %s", condition.Operator)
 	}
+	node := &compiledNode{condition: &condition}
+	if err := a.compileValue(node, factType); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
 
-	return false, fmt.Errorf("invalid rule structure: %s", string(node))
+// compileValue 检查条件的取值是否符合操作符和事实类型的要求，例如 before 需要时间事实、regex 需要合法的正则。
+// 取值与事实类型的匹配规则与 ConvertJSONToCEL 相同，保证能求值的规则都能迁移到 CEL。
+func (a *JSONRuleEngineAdapter) compileValue(node *compiledNode, factType reflect.Type) error {
+	c := node.condition
+	var err error
+	switch c.Operator {
+	case "equal", "notEqual":
+		_, _, err = celOperands(c.Fact, c.Value, factType)
+	case "greaterThan", "lessThan", "greaterThanInclusive", "lessThanInclusive":
+		if !isNumeric(factType) {
+			return fmt.Errorf("operator '%s' requires a numeric fact, but '%s' is %s", c.Operator, c.Fact, factType)
+		}
+		_, _, err = celOperands(c.Fact, c.Value, factType)
+	case "in", "notIn":
+		_, err = celList(c.Value, factType)
+	case "contains":
+		switch factType.Kind() {
+		case reflect.String:
+			_, err = celLiteral(c.Value, factType)
+		case reflect.Slice:
+			_, err = celLiteral(c.Value, factType.Elem())
+		default:
+			return fmt.Errorf("operator 'contains' requires an array or string fact, but '%s' is %s", c.Fact, factType)
+		}
+	case "containsAny":
+		if factType.Kind() != reflect.Slice {
+			return fmt.Errorf("operator 'containsAny' requires an array fact, but '%s' is %s", c.Fact, factType)
+		}
+		_, err = celList(c.Value, factType.Elem())
+	case "startsWith", "regex":
+		if factType.Kind() != reflect.String {
+			return fmt.Errorf("operator '%s' requires a string fact, but '%s' is %s", c.Operator, c.Fact, factType)
+		}
+		if _, err = celLiteral(c.Value, factType); err == nil && c.Operator == "regex" {
+			if node.pattern, err = regexp.Compile(c.Value.(string)); err != nil {
+				return fmt.Errorf("invalid regex for fact '%s': %w", c.Fact, err)
+			}
+		}
+	case "between":
+		if !isNumeric(factType) && factType != timeType {
+			return fmt.Errorf("operator 'between' requires a numeric or time fact, but '%s' is %s", c.Fact, factType)
+		}
+		bounds, ok := c.Value.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return fmt.Errorf("operator 'between' requires [min, max] for fact '%s'", c.Fact)
+		}
+		for _, b := range bounds {
+			if _, _, err = celOperands(c.Fact, b, factType); err != nil {
+				break
+			}
+		}
+	case "before", "after":
+		if factType != timeType {
+			return fmt.Errorf("operator '%s' requires a time fact, but '%s' is %s", c.Operator, c.Fact, factType)
+		}
+		_, err = celLiteral(c.Value, factType)
+	case "some", "every", "none":
+		if factType.Kind() != reflect.Slice || factType.Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("operator '%s' requires an array of objects, but fact '%s' is %s", c.Operator, c.Fact, factType)
+		}
+		nested, err := json.Marshal(c.Value)
+		if err != nil {
+			return fmt.Errorf("invalid nested rule for fact '%s': %w", c.Fact, err)
+		}
+		node.nested, err = a.compileNode(nested, factType.Elem())
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for fact '%s': %w", c.Fact, err)
+	}
+	return nil
+}
+
+// supportedOperators 列出 evaluateCondition 支持的所有操作符，新增操作符时需同步维护。
+var supportedOperators = map[string]bool{
+	"equal":                true,
+	"notEqual":             true,
+	"greaterThan":          true,
+	"lessThan":             true,
+	"greaterThanInclusive": true,
+	"lessThanInclusive":    true,
+	"in":                   true, // 事实值在给定数组中
+	"notIn":                true, // 事实值不在给定数组中
+	"contains":             true, // 数组事实包含给定元素，或字符串事实包含给定子串
+	"containsAny":          true, // 数组事实与给定数组有交集
+	"startsWith":           true, // 字符串事实以给定前缀开头
+	"regex":                true, // 字符串事实匹配给定正则
+	"between":              true, // 数值或时间事实落在 [min, max] 闭区间内
+	"before":               true, // 时间事实早于给定时间（RFC3339）
+	"after":                true, // 时间事实晚于给定时间（RFC3339）
+	"some":                 true, // 数组中至少一个元素满足嵌套规则
+	"every":                true, // 数组中所有元素都满足嵌套规则
+	"none":                 true, // 数组中没有元素满足嵌套规则
+}
+
+// evaluateNode 递归地评估一个已解析的节点（可以是条件组或单个条件）。
+// 同时出现 all 和 any 时只有 all 生效。
+func (a *JSONRuleEngineAdapter) evaluateNode(node *compiledNode, factMap map[string]interface{}) (bool, error) {
+	switch {
+	case len(node.all) > 0:
+		for _, subNode := range node.all {
+			match, err := a.evaluateNode(subNode, factMap)
+			if err != nil {
+				return false, err
+			}
+			if !match {
+				return false, nil // "all" 逻辑，一旦有一个不匹配，整个组就不匹配
+			}
+		}
+		return true, nil // 所有都匹配
+	case len(node.any) > 0:
+		for _, subNode := range node.any {
+			match, err := a.evaluateNode(subNode, factMap)
+			if err != nil {
+				return false, err
+			}
+			if match {
+				return true, nil // "any" 逻辑，一旦有一个匹配，整个组就匹配
+			}
+		}
+		return false, nil // 没有任何一个匹配
+	case node.not != nil:
+		match, err := a.evaluateNode(node.not, factMap)
+		if err != nil {
+			return false, err
+		}
+		return !match, nil
+	}
+	return a.evaluateCondition(node, factMap)
 }
 
 // evaluateCondition 评估单个条件。
-func (a *JSONRuleEngineAdapter) evaluateCondition(c *Condition, factMap map[string]interface{}) (bool, error) {
+func (a *JSONRuleEngineAdapter) evaluateCondition(node *compiledNode, factMap map[string]interface{}) (bool, error) {
+	c := node.condition
 	factValue, err := getFactValue(c.Fact, factMap)
 	if err != nil {
 		return false, err
@@ -100,15 +292,9 @@ func (a *JSONRuleEngineAdapter) evaluateCondition(c *Condition, factMap map[stri
 	// 核心比较逻辑
 	switch c.Operator {
 	case "equal":
-		if factIsNumber && condIsNumber {
-			return factValueFloat == condValueFloat, nil
-		}
-		return reflect.DeepEqual(factValue, c.Value), nil
+		return valuesEqual(factValue, c.Value), nil
 	case "notEqual":
-		if factIsNumber && condIsNumber {
-			return factValueFloat != condValueFloat, nil
-		}
-		return !reflect.DeepEqual(factValue, c.Value), nil
+		return !valuesEqual(factValue, c.Value), nil
 	case "greaterThan":
 		if !factIsNumber || !condIsNumber {
 			return false, fmt.Errorf("operator '%s' requires numeric values for fact '%s'", c.Operator, c.Fact)
@@ -129,12 +315,135 @@ func (a *JSONRuleEngineAdapter) evaluateCondition(c *Condition, factMap map[stri
 			return false, fmt.Errorf("operator '%s' requires numeric values for fact '%s'", c.Operator, c.Fact)
 		}
 		return factValueFloat <= condValueFloat, nil
-	// 您可以在这里轻松扩展更多操作符，例如 "in", "notIn", "contains" 等
+	case "in", "notIn":
+		list, ok := c.Value.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("operator '%s' requires an array value for fact '%s'", c.Operator, c.Fact)
+		}
+		return containsValue(list, factValue) == (c.Operator == "in"), nil
+	case "contains":
+		switch fv := factValue.(type) {
+		case nil:
+			return false, nil // 空数组在 JSON 中为 null
+		case []interface{}:
+			return containsValue(fv, c.Value), nil
+		case string:
+			sub, ok := c.Value.(string)
+			if !ok {
+				return false, fmt.Errorf("operator 'contains' requires a string value for fact '%s'", c.Fact)
+			}
+			return strings.Contains(fv, sub), nil
+		}
+		return false, fmt.Errorf("operator 'contains' requires an array or string fact '%s'", c.Fact)
+	case "containsAny":
+		list, ok := c.Value.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("operator 'containsAny' requires an array value for fact '%s'", c.Fact)
+		}
+		factList, _ := factValue.([]interface{})
+		if factValue != nil && factList == nil {
+			return false, fmt.Errorf("operator 'containsAny' requires an array fact '%s'", c.Fact)
+		}
+		for _, v := range list {
+			if containsValue(factList, v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "startsWith", "regex":
+		str, ok1 := factValue.(string)
+		arg, ok2 := c.Value.(string)
+		if !ok1 || !ok2 {
+			return false, fmt.Errorf("operator '%s' requires string values for fact '%s'", c.Operator, c.Fact)
+		}
+		if c.Operator == "startsWith" {
+			return strings.HasPrefix(str, arg), nil
+		}
+		return node.pattern.MatchString(str), nil
+	case "between":
+		bounds, ok := c.Value.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return false, fmt.Errorf("operator 'between' requires [min, max] for fact '%s'", c.Fact)
+		}
+		if factIsNumber {
+			low, ok1 := toFloat64(bounds[0])
+			high, ok2 := toFloat64(bounds[1])
+			if !ok1 || !ok2 {
+				return false, fmt.Errorf("operator 'between' requires numeric bounds for fact '%s'", c.Fact)
+			}
+			return factValueFloat >= low && factValueFloat <= high, nil
+		}
+		t, low, high, err := toTimes(factValue, bounds[0], bounds[1])
+		if err != nil {
+			return false, fmt.Errorf("operator 'between' requires numeric or time values for fact '%s': %w", c.Fact, err)
+		}
+		return !t.Before(low) && !t.After(high), nil
+	case "before", "after":
+		t, ref, _, err := toTimes(factValue, c.Value, c.Value)
+		if err != nil {
+			return false, fmt.Errorf("operator '%s' requires time values for fact '%s': %w", c.Operator, c.Fact, err)
+		}
+		if c.Operator == "before" {
+			return t.Before(ref), nil
+		}
+		return t.After(ref), nil
+	case "some", "every", "none":
+		return a.evaluateQuantifier(node, factValue)
 	default:
 		return false, fmt.Errorf("unsupported operator: %s", c.Operator)
 	}
 }
 
+// evaluateQuantifier 对数组事实中的每个元素评估嵌套规则，嵌套规则中的 fact 路径相对于元素本身。
+// 空数组上 some 为 false，every 和 none 为 true。
+func (a *JSONRuleEngineAdapter) evaluateQuantifier(node *compiledNode, factValue interface{}) (bool, error) {
+	c := node.condition
+	elements, _ := factValue.([]interface{})
+	if factValue != nil && elements == nil {
+		return false, fmt.Errorf("operator '%s' requires an array fact '%s'", c.Operator, c.Fact)
+	}
+
+	for _, element := range elements {
+		elementMap, ok := element.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("operator '%s' requires an array of objects for fact '%s'", c.Operator, c.Fact)
+		}
+		match, err := a.evaluateNode(node.nested, elementMap)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case match && c.Operator == "some":
+			return true, nil
+		case match && c.Operator == "none":
+			return false, nil
+		case !match && c.Operator == "every":
+			return false, nil
+		}
+	}
+	return c.Operator != "some", nil
+}
+
+// valuesEqual 比较两个值是否相等，数字统一按 float64 比较。
+func valuesEqual(a, b interface{}) bool {
+	af, aIsNumber := toFloat64(a)
+	bf, bIsNumber := toFloat64(b)
+	if aIsNumber && bIsNumber {
+		return af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// containsValue 判断 list 中是否存在与 value 相等的元素。
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, v := range list {
+		if valuesEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
 // getFactValue 通过点分路径 (e.g., "User.IsVip") 从 map 中获取值。
 func getFactValue(path string, data map[string]interface{}) (interface{}, error) {
 	parts := strings.Split(path, ".")
@@ -157,6 +466,23 @@ func getFactValue(path string, data map[string]interface{}) (interface{}, error)
 	return current, nil
 }
 
+// resolveFactType 按点分路径在给定类型中查找字段，返回字段的类型，用于在编译期发现拼写错误。
+// 路径的大小写规则与 getFactValue 一致。
+func resolveFactType(path string, root reflect.Type) (reflect.Type, error) {
+	current := root
+	for _, part := range strings.Split(path, ".") {
+		if part == "" || current.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("invalid path at '%s'", part)
+		}
+		field, ok := current.FieldByName(strings.ToUpper(part[:1]) + part[1:])
+		if !ok || field.Tag.Get("json") == "-" { // 不参与序列化的字段在求值时不可见
+			return nil, fmt.Errorf("fact not found: %s", path)
+		}
+		current = field.Type
+	}
+	return current, nil
+}
+
 // structToMap 将一个 struct 转换为 map[string]interface{} 以便进行动态访问。
 func structToMap(s interface{}) (map[string]interface{}, error) {
 	data, err := json.Marshal(s)
@@ -168,6 +494,28 @@ func structToMap(s interface{}) (map[string]interface{}, error) {
 	return result, err
 }
 
+// toTime 将 RFC3339 格式的字符串转换为时间，domain.Fact 中的时间字段序列化后也是这种格式。
+func toTime(v interface{}) (time.Time, error) {
+	str, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected an RFC3339 time string, got %v", v)
+	}
+	return time.Parse(time.RFC3339, str)
+}
+
+// toTimes 依次转换事实值和两个参照值。
+func toTimes(fact, first, second interface{}) (time.Time, time.Time, time.Time, error) {
+	values := make([]time.Time, 0, 3)
+	for _, v := range []interface{}{fact, first, second} {
+		t, err := toTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, time.Time{}, err
+		}
+		values = append(values, t)
+	}
+	return values[0], values[1], values[2], nil
+}
+
 // toFloat64 是一个辅助函数，尝试将 interface{} 转换为 float64。
 func toFloat64(v interface{}) (float64, bool) {
 	switch val := v.(type) {
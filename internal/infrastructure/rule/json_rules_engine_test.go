@@ -0,0 +1,122 @@
+// internal/infrastructure/rule/json_rules_engine_test.go
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestJSONRuleEngine_Operators(t *testing.T) {
+	fact := domain.Fact{
+		User: domain.UserContext{ID: 1, Labels: []string{"new_user", "student"}},
+		Items: []domain.CartItem{
+			{SKU: "FRESH-001", Price: 3000, Quantity: 1, Category: "fresh"},
+			{SKU: "BOOK-001", Price: 8000, Quantity: 2, Category: "book"},
+		},
+		Environment: domain.EnvironmentContext{Timestamp: time.Date(2026, 11, 11, 10, 0, 0, 0, time.UTC), Channel: "app"},
+		TotalAmount: 19000,
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		expected bool
+	}{
+		{"in", `{"fact": "Environment.Channel", "operator": "in", "value": ["app", "mini_program"]}`, true},
+		{"notIn", `{"fact": "Environment.Channel", "operator": "notIn", "value": ["app"]}`, false},
+		{"contains label", `{"fact": "User.Labels", "operator": "contains", "value": "student"}`, true},
+		{"containsAny label", `{"fact": "User.Labels", "operator": "containsAny", "value": ["vip", "new_user"]}`, true},
+		{"startsWith", `{"fact": "Environment.Channel", "operator": "startsWith", "value": "mini"}`, false},
+		{"regex", `{"fact": "Environment.Channel", "operator": "regex", "value": "^(app|web)$"}`, true},
+		{"between amount", `{"fact": "TotalAmount", "operator": "between", "value": [10000, 20000]}`, true},
+		{"between dates", `{"fact": "Environment.Timestamp", "operator": "between", "value": ["2026-11-11T00:00:00Z", "2026-11-11T23:59:59Z"]}`, true},
+		{"before", `{"fact": "Environment.Timestamp", "operator": "before", "value": "2026-11-01T00:00:00Z"}`, false},
+		{"after", `{"fact": "Environment.Timestamp", "operator": "after", "value": "2026-11-01T00:00:00Z"}`, true},
+		{"not", `{"not": {"fact": "User.Labels", "operator": "contains", "value": "vip"}}`, true},
+		{"some item", `{"fact": "Items", "operator": "some", "value": {"all": [
+			{"fact": "Category", "operator": "equal", "value": "book"},
+			{"fact": "Price", "operator": "greaterThan", "value": 5000}]}}`, true},
+		{"some item misses", `{"fact": "Items", "operator": "some", "value": {"all": [
+			{"fact": "Category", "operator": "equal", "value": "fresh"},
+			{"fact": "Price", "operator": "greaterThan", "value": 5000}]}}`, false},
+		{"every item", `{"fact": "Items", "operator": "every", "value": {"fact": "Quantity", "operator": "greaterThanInclusive", "value": 1}}`, true},
+		{"none item", `{"fact": "Items", "operator": "none", "value": {"fact": "SKU", "operator": "startsWith", "value": "FRESH-"}}`, false},
+	}
+
+	engine := NewJSONRuleEngineAdapter()
+	for _, tt := range tests {
+		if err := engine.Compile(jsonRule(tt.rule)); err != nil {
+			t.Errorf("%s: unexpected compile error: %v", tt.name, err)
+			continue
+		}
+		match, err := engine.Evaluate(jsonRule(tt.rule), fact)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if match != tt.expected {
+			t.Errorf("%s: expected %v; got %v", tt.name, tt.expected, match)
+		}
+	}
+}
+
+func TestJSONRuleEngine_CompileRejectsInvalidValues(t *testing.T) {
+	rules := []string{
+		`{"fact": "Environment.Channel", "operator": "in", "value": "app"}`,
+		`{"fact": "TotalAmount", "operator": "between", "value": [1]}`,
+		`{"fact": "Environment.Channel", "operator": "regex", "value": "("}`,
+		`{"fact": "Environment.Timestamp", "operator": "after", "value": "tomorrow"}`,
+		`{"fact": "TotalAmount", "operator": "some", "value": {"fact": "Price", "operator": "equal", "value": 1}}`,
+		`{"fact": "Items", "operator": "some", "value": {"fact": "Colour", "operator": "equal", "value": "red"}}`,
+		`{"not": {"fact": "User.Unknown", "operator": "equal", "value": 1}}`,
+		`{"all": [{"fact": "Cart.Total", "operator": "greaterThan", "value": 1}]}`,
+		`{"fact": "TotalAmount.Value", "operator": "equal", "value": 1}`,
+		`{"fact": "TotalAmount", "operator": "matches", "value": 1}`,
+		`{"operator": "equal", "value": 1}`,
+		`{"fact": "Items", "operator": "some", "value": {"fact": "Discount", "operator": "greaterThan", "value": 0}}`,
+		`{"fact": "TotalAmount", "operator": "before", "value": "2026-11-01T00:00:00Z"}`,
+		`{"fact": "Items", "operator": "some", "value": {"fact": "Price", "operator": "after", "value": "2026-11-01T00:00:00Z"}}`,
+		`{"fact": "User.IsVip", "operator": "equal", "value": "yes"}`,
+		`{"fact": "Environment.Channel", "operator": "greaterThan", "value": 1}`,
+		`{"fact": "TotalAmount", "operator": "in", "value": ["10000"]}`,
+		`{"fact": "User.Labels", "operator": "contains", "value": 1}`,
+		`{"fact": "TotalAmount", "operator": "regex", "value": "^1"}`,
+	}
+	engine := NewJSONRuleEngineAdapter()
+	for _, rule := range rules {
+		if err := engine.Compile(jsonRule(rule)); err == nil {
+			t.Errorf("expected a compile error for %s", rule)
+		}
+	}
+}
+
+func TestJSONRuleEngine_CachesCompiledRules(t *testing.T) {
+	definition := `{"fact": "Items", "operator": "some", "value": {"fact": "SKU", "operator": "regex", "value": "^BOOK-"}}`
+	engine := NewJSONRuleEngineAdapter()
+	if err := engine.Compile(jsonRule(definition)); err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+	cached, ok := engine.ruleCache.Load(definition)
+	if !ok {
+		t.Fatalf("expected the compiled rule to be cached")
+	}
+	node := cached.(*compiledNode)
+	if node.nested == nil || node.nested.pattern == nil {
+		t.Fatalf("expected the nested rule and its regex to be compiled ahead of evaluation; got %+v", node)
+	}
+
+	fact := domain.Fact{Items: []domain.CartItem{{SKU: "BOOK-1", Price: 100, Quantity: 1}}}
+	match, err := engine.Evaluate(jsonRule(definition), fact)
+	if err != nil || !match {
+		t.Fatalf("expected the cached rule to match; got %v (%v)", match, err)
+	}
+	if again, _ := engine.ruleCache.Load(definition); again != cached {
+		t.Errorf("expected evaluation to reuse the cached rule")
+	}
+}
+
+func jsonRule(definition string) domain.PromotionRule {
+	return domain.PromotionRule{Language: domain.RuleLanguageJSON, Definition: definition}
+}
@@ -0,0 +1,331 @@
+// promotion-service/internal/infrastructure/rule/json_to_cel.go
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// ConversionError 指出 JSON 规则中无法转换为 CEL 的条件。
+// Path 是该条件在规则树中的位置，例如 "all[1].any[0]"，根节点为空字符串。
+type ConversionError struct {
+	Path    string
+	Message string
+}
+
+func (e *ConversionError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ConvertJSONToCEL 将 RuleGroup/Condition 格式的 JSON 规则转换为语义等价的 CEL 表达式，
+// 使 JSON 规则也能走编译并缓存的 CEL 执行路径。空规则转换为空表达式，两者都视为无条件满足。
+// 与 JSONRuleEngineAdapter 的求值保持一致：同时出现 all 和 any 时只有 all 生效。
+// 转换失败时返回 *ConversionError。
+func ConvertJSONToCEL(definition string) (string, error) {
+	if definition == "" {
+		return "", nil
+	}
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(definition), &raw); err != nil {
+		return "", &ConversionError{Message: fmt.Sprintf("failed to unmarshal rule definition: %v", err)}
+	}
+	c := &celConverter{}
+	return c.node(raw, "", scope{variable: "fact", root: reflect.TypeOf(domain.Fact{})})
+}
+
+// scope 描述 fact 路径的解析起点：规则顶层为 fact 变量，数组量词内部为当前元素的变量。
+type scope struct {
+	variable string
+	root     reflect.Type
+	depth    int // 嵌套的量词层数，用于生成不冲突的变量名
+}
+
+type celConverter struct{}
+
+// node 转换一个条件组或单个条件，path 是该节点在规则树中的位置。
+func (c *celConverter) node(node json.RawMessage, path string, s scope) (string, error) {
+	var group RuleGroup
+	if json.Unmarshal(node, &group) == nil {
+		if len(group.All) > 0 {
+			return c.group(group.All, joinPath(path, "all"), " && ", s)
+		}
+		if len(group.Any) > 0 {
+			return c.group(group.Any, joinPath(path, "any"), " || ", s)
+		}
+		if len(group.Not) > 0 {
+			inner, err := c.node(group.Not, joinPath(path, "not"), s)
+			if err != nil {
+				return "", err
+			}
+			return "!(" + inner + ")", nil
+		}
+	}
+
+	var condition Condition
+	if err := json.Unmarshal(node, &condition); err != nil || condition.Fact == "" {
+		return "", &ConversionError{Path: path, Message: fmt.Sprintf("invalid rule structure: %s", string(node))}
+	}
+	expr, err := c.condition(&condition, path, s)
+	if err != nil {
+		if _, ok := err.(*ConversionError); ok {
+			return "", err // 来自数组量词内部的条件，已带有更精确的位置
+		}
+		return "", &ConversionError{Path: path, Message: err.Error()}
+	}
+	return expr, nil
+}
+
+// group 转换 all/any 条件组，每个子节点加上括号以保留原有的结合关系。
+func (c *celConverter) group(nodes []json.RawMessage, path, operator string, s scope) (string, error) {
+	parts := make([]string, 0, len(nodes))
+	for i, n := range nodes {
+		expr, err := c.node(n, fmt.Sprintf("%s[%d]", path, i), s)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "("+expr+")")
+	}
+	return strings.Join(parts, operator), nil
+}
+
+// condition 转换单个条件，返回的错误不带路径，由调用方补充。
+func (c *celConverter) condition(cond *Condition, path string, s scope) (string, error) {
+	factType, err := resolveFactType(cond.Fact, s.root)
+	if err != nil {
+		return "", err
+	}
+	field := s.variable + "." + celFieldPath(cond.Fact)
+
+	switch cond.Operator {
+	case "equal", "notEqual":
+		lhs, value, err := celOperands(field, cond.Value, factType)
+		if err != nil {
+			return "", err
+		}
+		if cond.Operator == "equal" {
+			return lhs + " == " + value, nil
+		}
+		return lhs + " != " + value, nil
+	case "greaterThan", "lessThan", "greaterThanInclusive", "lessThanInclusive":
+		if !isNumeric(factType) {
+			return "", fmt.Errorf("operator '%s' requires a numeric fact, but '%s' is %s", cond.Operator, cond.Fact, factType)
+		}
+		lhs, value, err := celOperands(field, cond.Value, factType)
+		if err != nil {
+			return "", err
+		}
+		return lhs + " " + comparisonOperators[cond.Operator] + " " + value, nil
+	case "in", "notIn":
+		list, err := celList(cond.Value, factType)
+		if err != nil {
+			return "", err
+		}
+		if cond.Operator == "in" {
+			return field + " in " + list, nil
+		}
+		return "!(" + field + " in " + list + ")", nil
+	case "contains":
+		if factType.Kind() == reflect.String {
+			value, err := celLiteral(cond.Value, factType)
+			if err != nil {
+				return "", err
+			}
+			return field + ".contains(" + value + ")", nil
+		}
+		if factType.Kind() != reflect.Slice {
+			return "", fmt.Errorf("operator 'contains' requires an array or string fact, but '%s' is %s", cond.Fact, factType)
+		}
+		value, err := celLiteral(cond.Value, factType.Elem())
+		if err != nil {
+			return "", err
+		}
+		return value + " in " + field, nil
+	case "containsAny":
+		if factType.Kind() != reflect.Slice {
+			return "", fmt.Errorf("operator 'containsAny' requires an array fact, but '%s' is %s", cond.Fact, factType)
+		}
+		list, err := celList(cond.Value, factType.Elem())
+		if err != nil {
+			return "", err
+		}
+		v := fmt.Sprintf("v%d", s.depth)
+		return fmt.Sprintf("%s.exists(%s, %s in %s)", field, v, v, list), nil
+	case "startsWith", "regex":
+		if factType.Kind() != reflect.String {
+			return "", fmt.Errorf("operator '%s' requires a string fact, but '%s' is %s", cond.Operator, cond.Fact, factType)
+		}
+		value, err := celLiteral(cond.Value, factType)
+		if err != nil {
+			return "", err
+		}
+		if cond.Operator == "startsWith" {
+			return field + ".startsWith(" + value + ")", nil
+		}
+		return field + ".matches(" + value + ")", nil
+	case "between":
+		if !isNumeric(factType) && factType != timeType {
+			return "", fmt.Errorf("operator 'between' requires a numeric or time fact, but '%s' is %s", cond.Fact, factType)
+		}
+		bounds, ok := cond.Value.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return "", fmt.Errorf("operator 'between' requires [min, max]")
+		}
+		lowLHS, low, err := celOperands(field, bounds[0], factType)
+		if err != nil {
+			return "", err
+		}
+		highLHS, high, err := celOperands(field, bounds[1], factType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s >= %s && %s <= %s", lowLHS, low, highLHS, high), nil
+	case "before", "after":
+		if factType != timeType {
+			return "", fmt.Errorf("operator '%s' requires a time fact, but '%s' is %s", cond.Operator, cond.Fact, factType)
+		}
+		value, err := celLiteral(cond.Value, factType)
+		if err != nil {
+			return "", err
+		}
+		if cond.Operator == "before" {
+			return field + " < " + value, nil
+		}
+		return field + " > " + value, nil
+	case "some", "every", "none":
+		if factType.Kind() != reflect.Slice || factType.Elem().Kind() != reflect.Struct {
+			return "", fmt.Errorf("operator '%s' requires an array of objects, but '%s' is %s", cond.Operator, cond.Fact, factType)
+		}
+		nested, err := json.Marshal(cond.Value)
+		if err != nil {
+			return "", fmt.Errorf("invalid nested rule: %v", err)
+		}
+		inner := scope{variable: fmt.Sprintf("item%d", s.depth), root: factType.Elem(), depth: s.depth + 1}
+		body, err := c.node(nested, joinPath(path, "value"), inner)
+		if err != nil {
+			return "", err
+		}
+		switch cond.Operator {
+		case "some":
+			return fmt.Sprintf("%s.exists(%s, %s)", field, inner.variable, body), nil
+		case "every":
+			return fmt.Sprintf("%s.all(%s, %s)", field, inner.variable, body), nil
+		}
+		return fmt.Sprintf("!%s.exists(%s, %s)", field, inner.variable, body), nil
+	}
+	return "", fmt.Errorf("unsupported operator: %s", cond.Operator)
+}
+
+var comparisonOperators = map[string]string{
+	"greaterThan":          ">",
+	"lessThan":             "<",
+	"greaterThanInclusive": ">=",
+	"lessThanInclusive":    "<=",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// celFieldPath 将点分路径转换为 CEL 中的字段访问，大小写规则与 getFactValue 一致。
+func celFieldPath(path string) string {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, ".")
+}
+
+// celOperands 返回比较两侧的表达式。JSON 引擎对数字统一按 float64 比较，
+// 整数字段与小数取值比较时需要先把字段转换为 double，否则 CEL 会因类型不同而编译失败。
+func celOperands(field string, value interface{}, t reflect.Type) (string, string, error) {
+	if f, ok := toFloat64(value); ok && isInteger(t) && f != math.Trunc(f) {
+		literal, err := celLiteral(value, reflect.TypeOf(float64(0)))
+		return "double(" + field + ")", literal, err
+	}
+	literal, err := celLiteral(value, t)
+	return field, literal, err
+}
+
+// celList 将数组取值转换为 CEL 列表字面量，元素按 elemType 转换。
+func celList(value interface{}, elemType reflect.Type) (string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("expected an array value, got %v", value)
+	}
+	items := make([]string, 0, len(list))
+	for _, v := range list {
+		item, err := celLiteral(v, elemType)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+	}
+	return "[" + strings.Join(items, ", ") + "]", nil
+}
+
+// celLiteral 按事实字段的类型把 JSON 取值转换为 CEL 字面量，类型不匹配时返回错误。
+// CEL 区分 int 和 double，整数字段上的小数取值需要经 celOperands 处理。
+func celLiteral(value interface{}, t reflect.Type) (string, error) {
+	switch {
+	case t == timeType:
+		tm, err := toTime(value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("timestamp(%s)", strconv.Quote(tm.Format(time.RFC3339Nano))), nil
+	case t.Kind() == reflect.Bool:
+		if b, ok := value.(bool); ok {
+			return strconv.FormatBool(b), nil
+		}
+	case t.Kind() == reflect.String:
+		if str, ok := value.(string); ok {
+			return strconv.Quote(str), nil
+		}
+	case isInteger(t):
+		if f, ok := toFloat64(value); ok {
+			if f != math.Trunc(f) {
+				return "", fmt.Errorf("expected an integer for %s, got %v", t, value)
+			}
+			return strconv.FormatInt(int64(f), 10), nil
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		if f, ok := toFloat64(value); ok {
+			s := strconv.FormatFloat(f, 'f', -1, 64)
+			if !strings.Contains(s, ".") {
+				s += ".0"
+			}
+			return s, nil
+		}
+	default:
+		return "", fmt.Errorf("unsupported fact type %s", t)
+	}
+	return "", fmt.Errorf("expected a %s value, got %v", t, value)
+}
+
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isNumeric(t reflect.Type) bool {
+	return isInteger(t) || t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+// joinPath 拼接规则树中的位置。
+func joinPath(path, part string) string {
+	if path == "" {
+		return part
+	}
+	return path + "." + part
+}
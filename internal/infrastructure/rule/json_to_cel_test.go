@@ -0,0 +1,93 @@
+// internal/infrastructure/rule/json_to_cel_test.go
+package rule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestConvertJSONToCEL_MatchesJSONEngine(t *testing.T) {
+	rules := []string{
+		`{"all": [{"fact": "User.IsVip", "operator": "equal", "value": true}, {"fact": "TotalAmount", "operator": "greaterThanInclusive", "value": 10000}]}`,
+		`{"any": [{"fact": "Environment.Channel", "operator": "in", "value": ["app", "web"]}, {"not": {"fact": "User.Labels", "operator": "contains", "value": "new_user"}}]}`,
+		`{"fact": "User.Labels", "operator": "containsAny", "value": ["student", "vip"]}`,
+		`{"fact": "Environment.Channel", "operator": "notIn", "value": ["mini_program"]}`,
+		`{"fact": "Shipping.Region", "operator": "regex", "value": "^(Shang|Bei)"}`,
+		`{"fact": "Shipping.Region", "operator": "startsWith", "value": "Xin"}`,
+		`{"fact": "TotalAmount", "operator": "between", "value": [9999.5, 20000]}`,
+		`{"fact": "Environment.Timestamp", "operator": "between", "value": ["2026-11-11T00:00:00Z", "2026-11-11T23:59:59Z"]}`,
+		`{"fact": "Environment.Timestamp", "operator": "before", "value": "2026-11-11T12:00:00+08:00"}`,
+		`{"fact": "Items", "operator": "some", "value": {"all": [{"fact": "Category", "operator": "equal", "value": "book"}, {"fact": "Price", "operator": "greaterThan", "value": 5000}]}}`,
+		`{"fact": "Items", "operator": "every", "value": {"fact": "Quantity", "operator": "lessThan", "value": 2}}`,
+		`{"fact": "Items", "operator": "none", "value": {"fact": "SKU", "operator": "startsWith", "value": "FRESH-"}}`,
+	}
+	facts := []domain.Fact{
+		{
+			User:        domain.UserContext{IsVip: true, Labels: []string{"student"}},
+			Items:       []domain.CartItem{{SKU: "BOOK-1", Price: 8000, Quantity: 1, Category: "book"}},
+			Environment: domain.EnvironmentContext{Timestamp: time.Date(2026, 11, 11, 3, 0, 0, 0, time.UTC), Channel: "app"},
+			Shipping:    domain.ShippingContext{Region: "Shanghai"},
+			TotalAmount: 10000,
+		},
+		{
+			User:        domain.UserContext{Labels: []string{"new_user"}},
+			Items:       []domain.CartItem{{SKU: "FRESH-1", Price: 3000, Quantity: 3, Category: "fresh"}},
+			Environment: domain.EnvironmentContext{Timestamp: time.Date(2026, 11, 12, 0, 0, 0, 0, time.UTC), Channel: "mini_program"},
+			Shipping:    domain.ShippingContext{Region: "Xinjiang"},
+			TotalAmount: 9000,
+		},
+	}
+
+	jsonEngine := NewJSONRuleEngineAdapter()
+	celEngine, err := NewCelRuleEngine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, definition := range rules {
+		expression, err := ConvertJSONToCEL(definition)
+		if err != nil {
+			t.Errorf("%s: unexpected conversion error: %v", definition, err)
+			continue
+		}
+		celRule := domain.PromotionRule{Language: domain.RuleLanguageCEL, Definition: expression}
+		if err := celEngine.Compile(celRule); err != nil {
+			t.Errorf("%s: converted expression %q does not compile: %v", definition, expression, err)
+			continue
+		}
+		for i, fact := range facts {
+			expected, err := jsonEngine.Evaluate(jsonRule(definition), fact)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", definition, err)
+			}
+			got, err := celEngine.Evaluate(celRule, fact)
+			if err != nil || got != expected {
+				t.Errorf("%s on fact %d: JSON engine returned %v, CEL %q returned %v (%v)", definition, i, expected, expression, got, err)
+			}
+		}
+	}
+}
+
+func TestConvertJSONToCEL_ErrorPointsAtCondition(t *testing.T) {
+	tests := []struct {
+		rule string
+		path string
+	}{
+		{`{"all": [{"fact": "User.IsVip", "operator": "equal", "value": true}, {"any": [{"fact": "TotalAmount", "operator": "startsWith", "value": "1"}]}]}`, "all[1].any[0]"},
+		{`{"fact": "Items", "operator": "some", "value": {"all": [{"fact": "Colour", "operator": "equal", "value": "red"}]}}`, "value.all[0]"},
+		{`{"not": {"fact": "User.IsVip", "operator": "equal", "value": "yes"}}`, "not"},
+	}
+	for _, tt := range tests {
+		_, err := ConvertJSONToCEL(tt.rule)
+		var convErr *ConversionError
+		if !errors.As(err, &convErr) {
+			t.Errorf("%s: expected a *ConversionError; got %v", tt.rule, err)
+			continue
+		}
+		if convErr.Path != tt.path {
+			t.Errorf("%s: expected path %q; got %q (%v)", tt.rule, tt.path, convErr.Path, convErr)
+		}
+	}
+}
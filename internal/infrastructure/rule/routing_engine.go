@@ -0,0 +1,47 @@
+// promotion-service/internal/infrastructure/rule/routing_engine.go
+package rule
+
+import (
+	"fmt"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+// RoutingRuleEngine 是一个组合的 domain.RuleEngine，按规则的语言把评估和编译分派给对应的引擎。
+// 运营工具生成的 JSON 规则和工程师编写的 CEL 规则因此可以在同一个服务中并存。
+type RoutingRuleEngine struct {
+	engines map[domain.RuleLanguage]domain.RuleEngine
+}
+
+// NewRoutingRuleEngine 创建一个按语言分派的规则引擎，engines 的键是该引擎负责的规则语言。
+func NewRoutingRuleEngine(engines map[domain.RuleLanguage]domain.RuleEngine) domain.RuleEngine {
+	return &RoutingRuleEngine{engines: engines}
+}
+
+// Evaluate 实现了 domain.RuleEngine 接口
+func (r *RoutingRuleEngine) Evaluate(rule domain.PromotionRule, fact domain.Fact) (bool, error) {
+	engine, err := r.engine(rule)
+	if err != nil {
+		return false, err
+	}
+	return engine.Evaluate(rule, fact)
+}
+
+// Compile 实现了 domain.RuleEngine 接口
+func (r *RoutingRuleEngine) Compile(rule domain.PromotionRule) error {
+	engine, err := r.engine(rule)
+	if err != nil {
+		return err
+	}
+	return engine.Compile(rule)
+}
+
+// engine 返回负责该规则语言的引擎，未指定语言时使用 domain.DefaultRuleLanguage。
+func (r *RoutingRuleEngine) engine(rule domain.PromotionRule) (domain.RuleEngine, error) {
+	language := rule.EffectiveLanguage()
+	engine, ok := r.engines[language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported rule language: %s", language)
+	}
+	return engine, nil
+}
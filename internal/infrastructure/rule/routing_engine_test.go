@@ -0,0 +1,40 @@
+// internal/infrastructure/rule/routing_engine_test.go
+package rule
+
+import (
+	"testing"
+
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+)
+
+func TestRoutingRuleEngine_DispatchesByLanguage(t *testing.T) {
+	celEngine, err := NewCelRuleEngine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	engine := NewRoutingRuleEngine(map[domain.RuleLanguage]domain.RuleEngine{
+		domain.RuleLanguageCEL:  celEngine,
+		domain.RuleLanguageJSON: NewJSONRuleEngineAdapter(),
+	})
+	fact := domain.Fact{User: domain.UserContext{IsVip: true}}
+
+	rules := []domain.PromotionRule{
+		{Definition: `fact.User.IsVip`}, // 未指定语言时按 CEL 处理
+		{Language: domain.RuleLanguageCEL, Definition: `fact.User.IsVip`},
+		{Language: domain.RuleLanguageJSON, Definition: `{"fact": "User.IsVip", "operator": "equal", "value": true}`},
+	}
+	for _, r := range rules {
+		match, err := engine.Evaluate(r, fact)
+		if err != nil || !match {
+			t.Errorf("%s rule %q: expected match; got %v, %v", r.Language, r.Definition, match, err)
+		}
+	}
+
+	// CEL 表达式按 JSON 编译应当失败，说明确实分派到了 JSON 引擎
+	if err := engine.Compile(domain.PromotionRule{Language: domain.RuleLanguageJSON, Definition: `fact.User.IsVip`}); err == nil {
+		t.Error("expected a CEL expression to be rejected by the JSON engine")
+	}
+	if err := engine.Compile(domain.PromotionRule{Language: "DROOLS", Definition: `x`}); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+}
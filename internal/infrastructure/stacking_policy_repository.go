@@ -0,0 +1,64 @@
+package infrastructure
+
+import (
+	"context"
+	"github.com/wangyingjie930/nexus-promotion/internal/domain"
+	"gorm.io/gorm"
+)
+
+type gormStackingPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStackingPolicyRepository(db *gorm.DB) domain.StackingPolicyRepository {
+	return &gormStackingPolicyRepository{db: db}
+}
+
+func (r *gormStackingPolicyRepository) FindAll(ctx context.Context) ([]*domain.StackingPolicy, error) {
+	var models []*StackingPolicyModel
+	if err := r.db.WithContext(ctx).Order("promotion_type").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	var policies []*domain.StackingPolicy
+	for _, model := range models {
+		policies = append(policies, toDomainStackingPolicy(model))
+	}
+	return policies, nil
+}
+
+func (r *gormStackingPolicyRepository) FindByPromotionType(ctx context.Context, promotionType string) (*domain.StackingPolicy, error) {
+	var model StackingPolicyModel
+	if err := r.db.WithContext(ctx).Where("promotion_type = ?", promotionType).First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toDomainStackingPolicy(&model), nil
+}
+
+func (r *gormStackingPolicyRepository) Save(ctx context.Context, policy *domain.StackingPolicy) error {
+	// 每种促销类型只有一条记录，已存在时沿用原记录的主键进行覆盖
+	existing, err := r.FindByPromotionType(ctx, policy.PromotionType)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		policy.ID = existing.ID
+		policy.CreatedAt = existing.CreatedAt
+	}
+
+	model := toGormStackingPolicy(policy)
+	if err := r.db.WithContext(ctx).Save(model).Error; err != nil {
+		return err
+	}
+	policy.ID = model.ID
+	policy.CreatedAt = model.CreatedAt
+	policy.UpdatedAt = model.UpdatedAt
+	return nil
+}
+
+func (r *gormStackingPolicyRepository) Delete(ctx context.Context, promotionType string) error {
+	return r.db.WithContext(ctx).Where("promotion_type = ?", promotionType).Delete(&StackingPolicyModel{}).Error
+}
@@ -60,3 +60,8 @@ func (p *gormRepoProvider) Templates() domain.PromotionTemplateRepository {
 	// 注意：这里传入的是事务句柄 tx
 	return NewGormPromotionTemplateRepository(p.db)
 }
+
+func (p *gormRepoProvider) StackingPolicies() domain.StackingPolicyRepository {
+	// 注意：这里传入的是事务句柄 tx
+	return NewGormStackingPolicyRepository(p.db)
+}
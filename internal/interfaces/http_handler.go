@@ -2,6 +2,8 @@ package interfaces
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/wangyingjie930/nexus-pkg/logger"
 	"github.com/wangyingjie930/nexus-promotion/internal/application"
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
@@ -29,10 +31,17 @@ func (h *PromotionHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("DELETE /templates/{groupId}", h.DeactivatePromotionTemplate)
 	mux.HandleFunc("GET /templates/{id}", h.GetPromotionTemplate)
 	mux.HandleFunc("GET /templates/group/{groupId}", h.GetActiveTemplateByGroup)
+	mux.HandleFunc("GET /discount-types", h.ListDiscountTypes)
+	mux.HandleFunc("POST /admin/rules/convert", h.ConvertRule)
+	mux.HandleFunc("GET /stacking-policies", h.ListStackingPolicies)
+	mux.HandleFunc("PUT /stacking-policies", h.SaveStackingPolicy)
+	mux.HandleFunc("DELETE /stacking-policies/{promotionType}", h.DeleteStackingPolicy)
 	mux.HandleFunc("POST /coupons/issue", h.IssueCouponToUser)
 	mux.HandleFunc("POST /coupons/issue-batch", h.IssueCouponsInBatch)
 	mux.HandleFunc("POST /offers/calculate-best", h.CalculateBestOffer)
+	mux.HandleFunc("POST /offers/quote", h.QuoteOffer)
 	mux.HandleFunc("POST /users/{userId}/applicable-coupons", h.GetApplicableCoupons)
+	mux.HandleFunc("POST /users/{userId}/checkout-coupons", h.GetCheckoutCoupons)
 	mux.HandleFunc("POST /users/{userId}/coupons/{couponCode}/freeze", h.FreezeUserCoupon)
 	mux.HandleFunc("POST /users/{userId}/coupons/{couponCode}/use", h.UseUserCoupon)
 	mux.HandleFunc("POST /users/{userId}/coupons/{couponCode}/unfreeze", h.UnfreezeUserCoupon)
@@ -49,7 +58,7 @@ func (h *PromotionHandler) CreatePromotionTemplate(w http.ResponseWriter, r *htt
 
 	resp, err := h.promoService.CreatePromotionTemplate(r.Context(), &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	json.NewEncoder(w).Encode(resp)
@@ -64,7 +73,7 @@ func (h *PromotionHandler) UpdatePromotionTemplate(w http.ResponseWriter, r *htt
 
 	resp, err := h.promoService.UpdatePromotionTemplate(r.Context(), &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	json.NewEncoder(w).Encode(resp)
@@ -113,6 +122,68 @@ func (h *PromotionHandler) GetActiveTemplateByGroup(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(resp)
 }
 
+func (h *PromotionHandler) ListDiscountTypes(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.promoService.ListDiscountTypes(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *PromotionHandler) ListStackingPolicies(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.promoService.ListStackingPolicies(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *PromotionHandler) ConvertRule(w http.ResponseWriter, r *http.Request) {
+	var req application.ConvertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.promoService.ConvertRule(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *PromotionHandler) SaveStackingPolicy(w http.ResponseWriter, r *http.Request) {
+	var req application.StackingPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.promoService.SaveStackingPolicy(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *PromotionHandler) DeleteStackingPolicy(w http.ResponseWriter, r *http.Request) {
+	promotionType := r.PathValue("promotionType")
+	if promotionType == "" {
+		http.Error(w, "promotionType is required", http.StatusBadRequest)
+		return
+	}
+	err := h.promoService.DeleteStackingPolicy(r.Context(), promotionType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *PromotionHandler) IssueCouponToUser(w http.ResponseWriter, r *http.Request) {
 	var req application.IssueCouponRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -147,9 +218,32 @@ func (h *PromotionHandler) CalculateBestOffer(w http.ResponseWriter, r *http.Req
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	resp, err := h.promoService.CalculateBestOffer(r.Context(), &fact)
+	explain, err := parseBoolQuery(r, "explain")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := application.OfferOptions{
+		Explain:   explain,
+		Objective: application.Objective(r.URL.Query().Get("objective")),
+	}
+	resp, err := h.promoService.CalculateBestOffer(r.Context(), &fact, opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *PromotionHandler) QuoteOffer(w http.ResponseWriter, r *http.Request) {
+	var req application.QuoteOfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := h.promoService.QuoteOffer(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 	json.NewEncoder(w).Encode(resp)
@@ -171,6 +265,21 @@ func (h *PromotionHandler) GetApplicableCoupons(w http.ResponseWriter, r *http.R
 
 	logger.Ctx(r.Context()).Info().Any("fact", fact).Send()
 
+	explain, err := parseBoolQuery(r, "explain")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if explain {
+		resp, err := h.promoService.ExplainApplicableCoupons(r.Context(), &fact, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
 	resp, err := h.promoService.GetApplicableCoupons(r.Context(), &fact, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -179,6 +288,28 @@ func (h *PromotionHandler) GetApplicableCoupons(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(resp)
 }
 
+func (h *PromotionHandler) GetCheckoutCoupons(w http.ResponseWriter, r *http.Request) {
+	userIDStr := r.PathValue("userId")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var fact domain.Fact
+	if err := json.NewDecoder(r.Body).Decode(&fact); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.promoService.GetCheckoutCoupons(r.Context(), &fact, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
 func (h *PromotionHandler) FreezeUserCoupon(w http.ResponseWriter, r *http.Request) {
 	userID, couponCode := h.parseUserAndCouponParams(r)
 	if userID == 0 || couponCode == "" {
@@ -221,6 +352,32 @@ func (h *PromotionHandler) UnfreezeUserCoupon(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusOK)
 }
 
+// writeError 将应用层错误转换为HTTP响应。
+// 校验错误返回 400 和结构化的问题列表，其余错误按服务端错误处理。
+func writeError(w http.ResponseWriter, err error) {
+	var verr *application.ValidationError
+	if errors.As(err, &verr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(verr)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// parseBoolQuery 解析一个可选的布尔查询参数，未提供时为 false
+func parseBoolQuery(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q", name, value)
+	}
+	return b, nil
+}
+
 // parseUserAndCouponParams 是一个辅助函数，用于从URL路径中解析参数
 func (h *PromotionHandler) parseUserAndCouponParams(r *http.Request) (int64, string) {
 	userIDStr := r.PathValue("userId")
@@ -4,9 +4,12 @@ package interfaces
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/wangyingjie930/nexus-promotion/internal/application"
 	"github.com/wangyingjie930/nexus-promotion/internal/domain"
 	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure"
+	"github.com/wangyingjie930/nexus-promotion/internal/infrastructure/rule"
 	"go.opentelemetry.io/otel"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -28,13 +31,14 @@ func setupTestServer(t *testing.T) *httptest.Server {
 	}
 
 	// 自动迁移数据库模型
-	err = db.AutoMigrate(&infrastructure.PromotionTemplateModel{}, &infrastructure.UserCouponModel{})
+	err = db.AutoMigrate(&infrastructure.PromotionTemplateModel{}, &infrastructure.UserCouponModel{}, &infrastructure.StackingPolicyModel{})
 	if err != nil {
 		t.Fatalf("failed to auto migrate models: %v", err)
 	}
 
 	db.Exec("TRUNCATE TABLE `promotion_template_models`")
 	db.Exec("TRUNCATE TABLE `user_coupon_models`")
+	db.Exec("TRUNCATE TABLE `stacking_policy_models`")
 
 	db.Exec(`INSERT INTO promotion_template_models (template_group_id, version, name, description, promotion_type, rule_definition, discount_type, discount_properties, start_date, end_date, is_exclusive, priority, is_active, created_at, updated_at)
 VALUES('group-new-user-100-20', 1, '新用户专享券', '新注册用户可领取的满100减20元优惠券', 'PLATFORM_SALE', 'fact.User.Labels.exists(label, label == "new_user")', 'FIXED_AMOUNT', '{"threshold": 10000, "amount": 2000}', '2025-01-01 00:00:00', '2025-12-31 23:59:59', 1, 100, 1, NOW(), NOW())`)
@@ -55,9 +59,21 @@ VALUES (456, 'NEW-USER-COUPON-456', 1, 'UNUSED', NOW(), '2025-12-31 23:59:59', N
 	// 依赖注入，与main.go中的逻辑保持一致
 	couponRepo := infrastructure.NewGormCouponRepository(db)
 	templateRepo := infrastructure.NewGormPromotionTemplateRepository(db)
+	stackingRepo := infrastructure.NewGormStackingPolicyRepository(db)
 	uow := infrastructure.NewGormUnitOfWork(db)
+	celEngine, err := rule.NewCelRuleEngine()
+	if err != nil {
+		t.Fatalf("failed to create cel rule engine: %v", err)
+	}
+	ruleEngine := rule.NewRoutingRuleEngine(map[domain.RuleLanguage]domain.RuleEngine{
+		domain.RuleLanguageCEL:  celEngine,
+		domain.RuleLanguageJSON: rule.NewJSONRuleEngineAdapter(),
+	})
 	tracer := otel.Tracer("test-tracer")
-	promoService := application.NewPromotionService(uow, templateRepo, couponRepo, tracer)
+	promoService, err := application.NewPromotionService(uow, templateRepo, couponRepo, stackingRepo, ruleEngine, tracer)
+	if err != nil {
+		t.Fatalf("failed to create promotion service: %v", err)
+	}
 	promoHandler := NewPromotionHandler(promoService)
 
 	// 创建 Mux 并注册路由
@@ -122,3 +138,29 @@ func TestCalculateBestOffer_VipUser_Success(t *testing.T) {
 		t.Errorf("expected strategy 'PercentageStrategy'; got '%s'", respBody.StrategyName)
 	}
 }
+
+func TestWriteError_ValidationErrorIsBadRequest(t *testing.T) {
+	verr := &application.ValidationError{
+		Message:  "invalid promotion template",
+		Problems: []*application.FieldError{{Field: "rule_definition", Message: "fact not found: User.Level"}},
+	}
+
+	rec := httptest.NewRecorder()
+	writeError(rec, fmt.Errorf("create template: %w", verr))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400; got %d", rec.Code)
+	}
+	var body application.ValidationError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v", err)
+	}
+	if body.Message != verr.Message || len(body.Problems) != 1 || body.Problems[0].Field != "rule_definition" {
+		t.Errorf("expected the structured problems in the body; got %+v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	writeError(rec, errors.New("database is down"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500; got %d", rec.Code)
+	}
+}